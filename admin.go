@@ -0,0 +1,136 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const (
+	HOST_ADMIN          = "https://admin.jpush.cn/v1/app"
+	CONTENT_TYPE_PKCS12 = "application/x-pkcs12"
+)
+
+// AdminClient Admin API 客户端，使用开发者账号的 dev key / dev secret 认证
+type AdminClient struct {
+	DevKey    string // dev key
+	DevSecret string // dev secret
+
+	transport http.RoundTripper
+}
+
+type AppRequest struct {
+	AppName        string `json:"app_name"`             // 应用名称
+	AndroidPackage string `json:"android_package"`      // 应用包名（Android）
+	GroupName      string `json:"group_name,omitempty"` // 应用分组名称
+}
+
+type AppResponse struct {
+	ResponseTiming
+	AppKey         string `json:"app_key"`         // 应用 appKey
+	AndroidPackage string `json:"android_package"` // 应用包名
+	IsNewCreated   bool   `json:"is_new_created"`  // 是否为新创建的应用，包名已存在时返回已有应用
+}
+
+type AdminResponse struct {
+	ResponseTiming
+	Success string `json:"success"` // 成功时为 "OK"
+}
+
+// CertificateFile APNs 证书文件（.p12）
+type CertificateFile struct {
+	Name     string    // 文件名
+	Reader   io.Reader // 文件内容，发送时以流的方式读取
+	Password string    // 证书密码
+}
+
+type CertificateRequest struct {
+	Dev *CertificateFile // 开发环境证书
+	Pro *CertificateFile // 生产环境证书
+}
+
+// NewAdminClient returns a new AdminClient
+func NewAdminClient(devKey string, devSecret string) *AdminClient {
+	return &AdminClient{DevKey: devKey, DevSecret: devSecret}
+}
+
+// SetTransport 设置客户端所有请求使用的 Transport，未设置时使用包内共用的连接池
+func (a *AdminClient) SetTransport(transport http.RoundTripper) {
+	a.transport = transport
+}
+
+// newRequest returns a request carrying the common JPush headers and the developer basic auth
+func (a *AdminClient) newRequest(method, url string) *HttpRequest {
+	req := newAuthRequest(method, url, a.DevKey, a.DevSecret)
+	if a.transport != nil {
+		req.SetTransport(a.transport)
+	}
+	return req
+}
+
+// CreateApp 创建应用
+func (a *AdminClient) CreateApp(r *AppRequest) (*AppResponse, error) {
+	if r == nil || r.AppName == "" || r.AndroidPackage == "" {
+		return nil, errors.New("app name and android package are required")
+	}
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := a.newRequest(http.MethodPost, HOST_ADMIN)
+	req.SetBody(body)
+
+	resp := &AppResponse{}
+	if err := sendJSON(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteApp 删除应用
+func (a *AdminClient) DeleteApp(appKey string) (*AdminResponse, error) {
+	if appKey == "" {
+		return nil, errors.New("app key is empty")
+	}
+
+	req := a.newRequest(http.MethodPost, HOST_ADMIN+"/"+url.PathEscape(appKey)+"/delete")
+
+	resp := &AdminResponse{}
+	if err := sendJSON(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UploadCertificate 上传 iOS APNs 证书，开发与生产证书至少提供一个
+func (a *AdminClient) UploadCertificate(appKey string, r *CertificateRequest) (*AdminResponse, error) {
+	if appKey == "" {
+		return nil, errors.New("app key is empty")
+	}
+	if r == nil || (r.Dev == nil && r.Pro == nil) {
+		return nil, errors.New("certificate is empty")
+	}
+	if (r.Dev != nil && r.Dev.Reader == nil) || (r.Pro != nil && r.Pro.Reader == nil) {
+		return nil, errors.New("certificate file is empty")
+	}
+
+	req := a.newRequest(http.MethodPost, HOST_ADMIN+"/"+url.PathEscape(appKey)+"/certificate")
+	if r.Dev != nil {
+		req.SetMultipartField("devCertificatePassword", r.Dev.Password)
+		req.SetMultipartFile("devCertificateFile", r.Dev.Name, CONTENT_TYPE_PKCS12, r.Dev.Reader)
+	}
+	if r.Pro != nil {
+		req.SetMultipartField("proCertificatePassword", r.Pro.Password)
+		req.SetMultipartFile("proCertificateFile", r.Pro.Name, CONTENT_TYPE_PKCS12, r.Pro.Reader)
+	}
+
+	resp := &AdminResponse{}
+	if err := sendJSON(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
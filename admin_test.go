@@ -0,0 +1,150 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// adminRequest is what the stub admin server saw
+type adminRequest struct {
+	method, path, user, password string
+	body                         map[string]interface{}
+	fields                       map[string]string
+}
+
+// recordAdminRequest decodes each request into seen
+func recordAdminRequest(t *testing.T, seen *adminRequest) func(*http.Request) {
+	return func(req *http.Request) {
+		*seen = adminRequest{method: req.Method, path: req.URL.Host + req.URL.EscapedPath(), fields: map[string]string{}}
+		seen.user, seen.password, _ = req.BasicAuth()
+
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+			if err := req.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("parse multipart: %v", err)
+			} else {
+				for k, v := range req.MultipartForm.Value {
+					seen.fields[k] = v[0]
+				}
+				for k, files := range req.MultipartForm.File {
+					f, _ := files[0].Open()
+					data, _ := io.ReadAll(f)
+					f.Close()
+					seen.fields[k] = files[0].Filename + ":" + files[0].Header.Get("Content-Type") + ":" + string(data)
+				}
+			}
+		} else if req.Body != nil {
+			data, _ := io.ReadAll(req.Body)
+			_ = json.Unmarshal(data, &seen.body)
+		}
+	}
+}
+
+func TestAdminCreateApp(t *testing.T) {
+	var seen adminRequest
+	a := NewAdminClient("dev-key", "dev-secret")
+	a.SetTransport(stubTransport(http.StatusOK, `{"app_key":"k1","android_package":"com.example","is_new_created":true}`, recordAdminRequest(t, &seen)))
+
+	ret, err := a.CreateApp(&AppRequest{AppName: "shop", AndroidPackage: "com.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen.method != http.MethodPost || seen.path != "admin.jpush.cn/v1/app" || seen.user != "dev-key" || seen.password != "dev-secret" {
+		t.Fatalf("request = %+v", seen)
+	}
+	if seen.body["app_name"] != "shop" || seen.body["android_package"] != "com.example" {
+		t.Fatalf("body = %v", seen.body)
+	}
+	if _, ok := seen.body["group_name"]; ok {
+		t.Fatalf("body = %v, an empty group name should be omitted", seen.body)
+	}
+	if ret.AppKey != "k1" || !ret.IsNewCreated {
+		t.Fatalf("response = %+v", ret)
+	}
+
+	seen = adminRequest{}
+	if _, err := a.CreateApp(&AppRequest{AppName: "shop"}); err == nil || seen.method != "" {
+		t.Fatal("a request without android package should be rejected before sending")
+	}
+}
+
+func TestAdminDeleteApp(t *testing.T) {
+	var seen adminRequest
+	a := NewAdminClient("dev-key", "dev-secret")
+	a.SetTransport(stubTransport(http.StatusOK, `{"success":"OK"}`, recordAdminRequest(t, &seen)))
+
+	ret, err := a.DeleteApp("k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen.method != http.MethodPost || seen.path != "admin.jpush.cn/v1/app/k1/delete" || seen.user != "dev-key" || ret.Success != "OK" {
+		t.Fatalf("request = %+v, response = %+v", seen, ret)
+	}
+
+	a.SetTransport(stubTransport(http.StatusUnauthorized, `{"error":{"code":1004,"message":"authentication failed"}}`, recordAdminRequest(t, &seen)))
+	var apiErr *APIError
+	if _, err := a.DeleteApp("k1"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != 1004 {
+		t.Fatalf("err = %v, want an *APIError", err)
+	}
+	if _, err := a.DeleteApp(""); err == nil {
+		t.Fatal("an empty app key should be rejected")
+	}
+
+	// app keys are escaped as one path segment
+	a.SetTransport(stubTransport(http.StatusOK, `{"success":"OK"}`, recordAdminRequest(t, &seen)))
+	if _, err := a.DeleteApp("k1/../k2"); err != nil {
+		t.Fatal(err)
+	}
+	if seen.path != "admin.jpush.cn/v1/app/k1%2F..%2Fk2/delete" {
+		t.Fatalf("path = %s", seen.path)
+	}
+}
+
+func TestAdminUploadCertificate(t *testing.T) {
+	var seen adminRequest
+	a := NewAdminClient("dev-key", "dev-secret")
+	a.SetTransport(stubTransport(http.StatusOK, `{"success":"OK"}`, recordAdminRequest(t, &seen)))
+
+	_, err := a.UploadCertificate("k1", &CertificateRequest{
+		Dev: &CertificateFile{Name: "dev.p12", Reader: strings.NewReader("dev-cert"), Password: "dev-pass"},
+		Pro: &CertificateFile{Name: "pro.p12", Reader: strings.NewReader("pro-cert"), Password: "pro-pass"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen.method != http.MethodPost || seen.path != "admin.jpush.cn/v1/app/k1/certificate" || seen.user != "dev-key" {
+		t.Fatalf("request = %+v", seen)
+	}
+	want := map[string]string{
+		"devCertificatePassword": "dev-pass",
+		"devCertificateFile":     "dev.p12:" + CONTENT_TYPE_PKCS12 + ":dev-cert",
+		"proCertificatePassword": "pro-pass",
+		"proCertificateFile":     "pro.p12:" + CONTENT_TYPE_PKCS12 + ":pro-cert",
+	}
+	for k, v := range want {
+		if seen.fields[k] != v {
+			t.Fatalf("field %s = %q, want %q", k, seen.fields[k], v)
+		}
+	}
+
+	// a single environment only sends its own fields
+	if _, err := a.UploadCertificate("k1", &CertificateRequest{Pro: &CertificateFile{Name: "pro.p12", Reader: strings.NewReader("pro-cert")}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := seen.fields["devCertificateFile"]; ok || seen.fields["proCertificateFile"] == "" {
+		t.Fatalf("fields = %v", seen.fields)
+	}
+
+	seen = adminRequest{}
+	for _, r := range []*CertificateRequest{nil, {}, {Dev: &CertificateFile{Name: "dev.p12"}}} {
+		if _, err := a.UploadCertificate("k1", r); err == nil {
+			t.Fatalf("request %+v should be rejected", r)
+		}
+	}
+	if seen.method != "" {
+		t.Fatal("invalid requests should not be sent")
+	}
+}
@@ -0,0 +1,227 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrQueueFull    = errors.New("jpush: push queue is full")
+	ErrPushDropped  = errors.New("jpush: push dropped because the queue is full")
+	ErrPusherClosed = errors.New("jpush: async pusher is shut down")
+)
+
+type OverflowPolicy int
+
+const (
+	OVERFLOW_BLOCK OverflowPolicy = iota // 队列满时阻塞等待，直到有空位或 ctx 结束
+	OVERFLOW_DROP                        // 队列满时丢弃，返回的 PushFuture 立即以 ErrPushDropped 完成
+	OVERFLOW_ERROR                       // 队列满时 Submit 返回 ErrQueueFull
+)
+
+// AsyncPusherOptions 异步推送参数，零值字段使用默认值
+type AsyncPusherOptions struct {
+	QueueSize int                 // 队列长度，默认 1000
+	Workers   int                 // 并发发送的 worker 数量，默认 4
+	Overflow  OverflowPolicy      // 队列满时的处理策略，默认阻塞
+	Lane      string              // 可选，推送使用的优先级通道，见 EnableLanes
+	Results   chan<- *AsyncResult // 可选，每个推送完成后将结果发送到该 channel，调用方需持续读取
+}
+
+// AsyncResult 异步推送结果
+type AsyncResult struct {
+	Data   []byte      // 推送内容
+	Result *PushResult // 推送成功的结果
+	Err    error       // 推送失败的原因
+}
+
+// PushFuture 异步推送的结果凭证
+type PushFuture struct {
+	done   chan struct{}
+	result *AsyncResult
+}
+
+// Done 返回推送完成时关闭的 channel
+func (f *PushFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait 等待推送完成并返回结果，ctx 结束时返回 ctx 的错误，推送仍会继续
+func (f *PushFuture) Wait(ctx context.Context) (*PushResult, error) {
+	select {
+	case <-f.done:
+		return f.result.Result, f.result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *PushFuture) complete(r *AsyncResult) {
+	f.result = r
+	close(f.done)
+}
+
+type asyncItem struct {
+	data   []byte
+	future *PushFuture
+}
+
+// AsyncPusher 基于 JPushClient 的异步推送队列，使用有界队列与固定数量的 worker 发送推送
+type AsyncPusher struct {
+	client  *JPushClient
+	opts    AsyncPusherOptions
+	queue   chan *asyncItem
+	closing chan struct{}
+
+	mu       sync.RWMutex
+	closed   bool
+	shutdown sync.Once
+
+	ctx    context.Context // canceled with the error of the Shutdown ctx when it gives up on pending pushes
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+}
+
+// NewAsyncPusher 创建异步推送队列并启动 worker，推送同样经过客户端的去重与合并（见 EnableDedup、EnableCoalescing）
+func NewAsyncPusher(j *JPushClient, opts *AsyncPusherOptions) *AsyncPusher {
+	o := AsyncPusherOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 1000
+	}
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+
+	a := &AsyncPusher{
+		client:  j,
+		opts:    o,
+		queue:   make(chan *asyncItem, o.QueueSize),
+		closing: make(chan struct{}),
+	}
+	a.ctx, a.cancel = context.WithCancelCause(WithLane(context.Background(), o.Lane))
+
+	for i := 0; i < o.Workers; i++ {
+		a.wg.Add(1)
+		go a.work()
+	}
+
+	return a
+}
+
+// Submit 将推送内容放入队列，返回可等待结果的 PushFuture。
+// 队列满时按 Overflow 策略处理，ctx 仅用于阻塞等待入队。
+func (a *AsyncPusher) Submit(ctx context.Context, data []byte) (*PushFuture, error) {
+	item := &asyncItem{data: data, future: &PushFuture{done: make(chan struct{})}}
+
+	dropped, err := a.enqueue(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	if dropped {
+		// outside the lock, delivering to Results may block until the caller reads it
+		a.finish(item, &AsyncResult{Data: data, Err: ErrPushDropped})
+	}
+	return item.future, nil
+}
+
+// enqueue puts item into the queue, it reports whether the item was dropped by the overflow policy
+func (a *AsyncPusher) enqueue(ctx context.Context, item *asyncItem) (bool, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return false, ErrPusherClosed
+	}
+
+	select {
+	case a.queue <- item:
+		return false, nil
+	default:
+	}
+
+	switch a.opts.Overflow {
+	case OVERFLOW_DROP:
+		return true, nil
+	case OVERFLOW_ERROR:
+		return false, ErrQueueFull
+	}
+
+	select {
+	case a.queue <- item:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-a.closing:
+		return false, ErrPusherClosed
+	}
+}
+
+// Len 返回队列中等待发送的推送数量
+func (a *AsyncPusher) Len() int {
+	return len(a.queue)
+}
+
+// Shutdown 停止接收新的推送并等待队列中与发送中的推送完成。
+// ctx 结束时取消仍在发送的推送，未发送的推送以 ctx 的错误完成，并返回 ctx 的错误。
+func (a *AsyncPusher) Shutdown(ctx context.Context) error {
+	first := false
+	a.shutdown.Do(func() {
+		first = true
+		// unblock submitters waiting for queue space before taking the write lock
+		close(a.closing)
+	})
+	if !first {
+		return ErrPusherClosed
+	}
+
+	a.mu.Lock()
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		a.cancel(nil)
+		return nil
+	case <-ctx.Done():
+		a.cancel(ctx.Err())
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (a *AsyncPusher) work() {
+	defer a.wg.Done()
+
+	for item := range a.queue {
+		if err := context.Cause(a.ctx); err != nil {
+			a.finish(item, &AsyncResult{Data: item.data, Err: err})
+			continue
+		}
+
+		ret, err := a.client.pushFrontResult(a.ctx, item.data)
+		a.finish(item, &AsyncResult{Data: item.data, Result: ret, Err: err})
+	}
+}
+
+// finish completes the future and publishes the result to the results channel
+func (a *AsyncPusher) finish(item *asyncItem, r *AsyncResult) {
+	item.future.complete(r)
+
+	if a.opts.Results != nil {
+		select {
+		case a.opts.Results <- r:
+		case <-a.ctx.Done():
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package jpush
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncPusherOverflow(t *testing.T) {
+	// no workers are able to send, the single queue slot stays occupied
+	a := &AsyncPusher{
+		opts:    AsyncPusherOptions{QueueSize: 1, Overflow: OVERFLOW_ERROR},
+		queue:   make(chan *asyncItem, 1),
+		closing: make(chan struct{}),
+	}
+	a.ctx, a.cancel = context.WithCancelCause(context.Background())
+
+	if _, err := a.Submit(context.Background(), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Submit(context.Background(), []byte("{}")); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	a.opts.Overflow = OVERFLOW_DROP
+	f, err := a.Submit(context.Background(), []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Wait(context.Background()); err != ErrPushDropped {
+		t.Errorf("expected ErrPushDropped, got %v", err)
+	}
+
+	a.opts.Overflow = OVERFLOW_BLOCK
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := a.Submit(ctx, []byte("{}")); err != context.DeadlineExceeded {
+		t.Errorf("expected blocking submit to time out, got %v", err)
+	}
+
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Submit(context.Background(), []byte("{}")); err != ErrPusherClosed {
+		t.Errorf("expected ErrPusherClosed, got %v", err)
+	}
+}
+
+// asyncTransport answers every push with its sequence number as msg_id and records the alerts in order
+func asyncTransport(release <-chan struct{}) (http.RoundTripper, func() []string) {
+	var mu sync.Mutex
+	var alerts []string
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if release != nil {
+			select {
+			case <-release:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+		var body struct {
+			Notification Notification `json:"notification"`
+		}
+		data, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(data, &body)
+
+		mu.Lock()
+		alerts = append(alerts, body.Notification.Alert)
+		n := len(alerts)
+		mu.Unlock()
+		return stubResponse(req, http.StatusOK, fmt.Sprintf(`{"sendno":"0","msg_id":"%d"}`, n)), nil
+	})
+	return rt, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), alerts...)
+	}
+}
+
+func asyncPayload(alert string) []byte {
+	return []byte(fmt.Sprintf(`{"platform":"all","audience":"all","notification":{"alert":%q}}`, alert))
+}
+
+func TestAsyncPusherOrderAndDrain(t *testing.T) {
+	release := make(chan struct{})
+	rt, sent := asyncTransport(release)
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(rt)
+
+	results := make(chan *AsyncResult, 10)
+	a := NewAsyncPusher(c, &AsyncPusherOptions{Workers: 1, QueueSize: 10, Results: results})
+	var futures []*PushFuture
+	for i := 0; i < 5; i++ {
+		f, err := a.Submit(context.Background(), asyncPayload(fmt.Sprint(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		futures = append(futures, f)
+	}
+
+	// Shutdown waits for the queued pushes to be sent
+	done := make(chan error)
+	go func() { done <- a.Shutdown(context.Background()) }()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(sent(), ","); got != "0,1,2,3,4" {
+		t.Fatalf("sent = %s, want the submission order", got)
+	}
+	for i, f := range futures {
+		ret, err := f.Wait(context.Background())
+		if err != nil || ret.MsgID != MsgID(i+1) {
+			t.Fatalf("push %d = %v, %v", i, ret, err)
+		}
+	}
+	if len(results) != 5 {
+		t.Fatalf("%d results published, want 5", len(results))
+	}
+}
+
+func TestAsyncPusherShutdownTimeout(t *testing.T) {
+	rt, sent := asyncTransport(make(chan struct{})) // never answers
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(rt)
+
+	a := NewAsyncPusher(c, &AsyncPusherOptions{Workers: 1, QueueSize: 10})
+	inflight, _ := a.Submit(context.Background(), asyncPayload("inflight"))
+	queued, _ := a.Submit(context.Background(), asyncPayload("queued"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := a.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown = %v, want the ctx error", err)
+	}
+	if _, err := inflight.Wait(context.Background()); err == nil {
+		t.Fatal("the push in flight should be canceled")
+	}
+	if _, err := queued.Wait(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unsent push = %v, want the ctx error", err)
+	}
+	if len(sent()) != 0 {
+		t.Fatalf("sent = %v", sent())
+	}
+}
+
+func TestAsyncPusherDropDoesNotBlockShutdown(t *testing.T) {
+	release := make(chan struct{})
+	rt, _ := asyncTransport(release)
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(rt)
+
+	// nobody reads the results until Shutdown returns
+	results := make(chan *AsyncResult)
+	a := NewAsyncPusher(c, &AsyncPusherOptions{Workers: 1, QueueSize: 1, Overflow: OVERFLOW_DROP, Results: results})
+	_, _ = a.Submit(context.Background(), asyncPayload("inflight"))
+	for len(a.queue) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, _ = a.Submit(context.Background(), asyncPayload("queued"))
+
+	dropped := make(chan error)
+	go func() {
+		f, err := a.Submit(context.Background(), asyncPayload("dropped"))
+		if err == nil {
+			_, err = f.Wait(context.Background())
+		}
+		dropped <- err
+	}()
+	time.Sleep(10 * time.Millisecond) // let the dropped push block on the results channel
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := a.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown = %v", err)
+	}
+	if err := <-dropped; err != ErrPushDropped {
+		t.Fatalf("dropped push = %v, want ErrPushDropped", err)
+	}
+}
+
+func TestAsyncPusherUsesDedup(t *testing.T) {
+	rt, sent := asyncTransport(nil)
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(rt)
+	c.EnableDedup(nil)
+
+	a := NewAsyncPusher(c, &AsyncPusherOptions{Workers: 1})
+	first, _ := a.Submit(context.Background(), asyncPayload("once"))
+	second, _ := a.Submit(context.Background(), asyncPayload("once"))
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ret, err := first.Wait(context.Background()); err != nil || ret.MsgID != 1 {
+		t.Fatalf("first push = %v, %v", ret, err)
+	}
+	if _, err := second.Wait(context.Background()); err != ErrDuplicatePush {
+		t.Fatalf("second push = %v, want ErrDuplicatePush", err)
+	}
+	if len(sent()) != 1 {
+		t.Fatalf("sent = %v", sent())
+	}
+}
@@ -1,6 +1,7 @@
 package jpush
 
 import (
+	"encoding/json"
 	"log"
 )
 
@@ -91,3 +92,26 @@ func (a *Audience) set(key AudienceType, v interface{}) {
 
 	a.audience[key] = v
 }
+
+// MarshalJSON 将推送目标序列化为 "all" 或推送目标对象
+func (a *Audience) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Interface())
+}
+
+// UnmarshalJSON 解析 "all" 或推送目标对象
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var all string
+	if err := json.Unmarshal(data, &all); err == nil {
+		a.Object = all
+		a.audience = nil
+		return nil
+	}
+
+	var m map[AudienceType]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	a.audience = m
+	a.Object = m
+	return nil
+}
@@ -0,0 +1,151 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+const (
+	HOST_BATCH_PUSH_REGID = "https://api.jpush.cn/v3/push/batch/regid/single"
+	HOST_BATCH_PUSH_ALIAS = "https://api.jpush.cn/v3/push/batch/alias/single"
+
+	BATCH_PUSH_MAX = 1000 // 单次批量推送最多支持的推送目标数量
+)
+
+type BatchTargetType string
+
+const (
+	BATCH_REGID BatchTargetType = "regid" // 按注册 ID 推送
+	BATCH_ALIAS BatchTargetType = "alias" // 按别名推送
+)
+
+// BatchPushResult 批量推送中单个推送目标的结果，Timing 为该目标所在批次请求的耗时
+type BatchPushResult struct {
+	ResponseTiming
+	MsgID MsgID // 消息 ID，推送失败时为 0
+	Error error // 推送失败的原因，JPush 返回的错误为 *APIError
+}
+
+type batchPushItem struct {
+	Platform     interface{}   `json:"platform"`
+	Target       string        `json:"target"`
+	Notification *Notification `json:"notification,omitempty"`
+	Message      *Message      `json:"message,omitempty"`
+	LiveActivity *LiveActivity `json:"live_activity,omitempty"`
+	Options      *Options      `json:"options,omitempty"`
+}
+
+type batchPushResponse map[string]struct {
+	MsgID MsgID     `json:"msg_id"`
+	Error *APIError `json:"error"`
+}
+
+// BatchPush 批量单推，为每个推送目标（注册 ID 或别名）发送各自的推送内容。
+// payloads 的 key 为推送目标，PayLoad 中的 Audience 与 Cid 会被忽略，未设置 Platform 时推送到所有平台。
+// 超过 1000 个推送目标时自动分批请求，返回每个推送目标的结果；单批请求失败时该批所有推送目标均返回该错误。
+func (j *JPushClient) BatchPush(targetType BatchTargetType, payloads map[string]*PayLoad) (map[string]*BatchPushResult, error) {
+	var url string
+	switch targetType {
+	case BATCH_REGID:
+		url = HOST_BATCH_PUSH_REGID
+	case BATCH_ALIAS:
+		url = HOST_BATCH_PUSH_ALIAS
+	default:
+		return nil, errors.New("invalid batch target type")
+	}
+	if len(payloads) == 0 {
+		return nil, errors.New("payloads is empty")
+	}
+
+	targets := make([]string, 0, len(payloads))
+	for target, p := range payloads {
+		if target == "" || p == nil {
+			return nil, errors.New("target and payload must not be empty")
+		}
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	ret := make(map[string]*BatchPushResult, len(targets))
+	for start := 0; start < len(targets); start += BATCH_PUSH_MAX {
+		end := start + BATCH_PUSH_MAX
+		if end > len(targets) {
+			end = len(targets)
+		}
+
+		chunk := targets[start:end]
+		if err := j.sendBatchPush(url, chunk, payloads, ret); err != nil {
+			for _, target := range chunk {
+				ret[target] = &BatchPushResult{Error: err}
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// sendBatchPush sends one batch request for targets and stores the per target results into ret
+func (j *JPushClient) sendBatchPush(url string, targets []string, payloads map[string]*PayLoad, ret map[string]*BatchPushResult) error {
+	cids, err := j.getCidList(len(targets), "push")
+	if err != nil {
+		return err
+	}
+	if len(cids) < len(targets) {
+		return fmt.Errorf("got %d cids for %d targets", len(cids), len(targets))
+	}
+
+	pushList := make(map[string]*batchPushItem, len(targets))
+	cidTargets := make(map[string]string, len(targets))
+	for i, target := range targets {
+		p := payloads[target]
+		item := &batchPushItem{
+			Platform:     "all",
+			Target:       target,
+			Notification: p.Notification,
+			Message:      p.Message,
+			LiveActivity: p.LiveActivity,
+			Options:      p.Options,
+		}
+		if p.Platform != nil {
+			item.Platform = p.Platform.Interface()
+		}
+		pushList[cids[i]] = item
+		cidTargets[cids[i]] = target
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"pushlist": pushList})
+	if err != nil {
+		return err
+	}
+	body, err = j.withDefaults(body, "pushlist", "*")
+	if err != nil {
+		return err
+	}
+
+	req := j.newRequest(http.MethodPost, url)
+	req.SetBody(body)
+
+	resp := batchPushResponse{}
+	if err := sendJSON(req, &resp); err != nil {
+		return err
+	}
+
+	timing := ResponseTiming{Timing: req.Timing()}
+	for cid, target := range cidTargets {
+		r, ok := resp[cid]
+		switch {
+		case !ok:
+			ret[target] = &BatchPushResult{ResponseTiming: timing, Error: errors.New("no result returned for target")}
+		case r.Error != nil:
+			r.Error.StatusCode = http.StatusOK
+			ret[target] = &BatchPushResult{ResponseTiming: timing, Error: r.Error}
+		default:
+			ret[target] = &BatchPushResult{ResponseTiming: timing, MsgID: r.MsgID}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchPush(t *testing.T) {
+	var nextCid int64
+	var batches []map[string]batchPushItem
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		respond := func(status int, body string) (*http.Response, error) {
+			return stubResponse(req, status, body), nil
+		}
+
+		if req.URL.Path == "/v3/push/cid" {
+			n, _ := strconv.Atoi(req.URL.Query().Get("count"))
+			cids := make([]string, n)
+			for i := range cids {
+				cids[i] = fmt.Sprintf(`"cid-%d"`, atomic.AddInt64(&nextCid, 1))
+			}
+			return respond(http.StatusOK, `{"cidlist":[`+strings.Join(cids, ",")+`]}`)
+		}
+		if req.URL.Path != "/v3/push/batch/alias/single" {
+			t.Errorf("unexpected request %s", req.URL.Path)
+		}
+
+		var sent struct {
+			PushList map[string]batchPushItem `json:"pushlist"`
+		}
+		data, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(data, &sent); err != nil {
+			t.Error(err)
+		}
+		batches = append(batches, sent.PushList)
+
+		// the second batch fails as a whole
+		if len(batches) == 2 {
+			return respond(http.StatusInternalServerError, `{"error":{"code":1000,"message":"internal error"}}`)
+		}
+
+		results := make([]string, 0, len(sent.PushList))
+		for cid, item := range sent.PushList {
+			switch item.Target {
+			case "u0001":
+				results = append(results, fmt.Sprintf(`%q:{"error":{"code":1011,"message":"cannot find user by this audience"}}`, cid))
+			case "u0002":
+				// no result for this target
+			default:
+				results = append(results, fmt.Sprintf(`%q:{"msg_id":"%s"}`, cid, strings.TrimPrefix(item.Target, "u")))
+			}
+		}
+		return respond(http.StatusOK, "{"+strings.Join(results, ",")+"}")
+	}))
+
+	payloads := make(map[string]*PayLoad)
+	for i := 0; i < 2*BATCH_PUSH_MAX+500; i++ {
+		p := NewPayLoad()
+		p.SetNotification(&Notification{Alert: fmt.Sprint(i)})
+		payloads[fmt.Sprintf("u%04d", i)] = p
+	}
+	ret, err := c.BatchPush(BATCH_ALIAS, payloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1000 targets per request, every target with its own cid and payload
+	if len(batches) != 3 || len(batches[0]) != BATCH_PUSH_MAX || len(batches[1]) != BATCH_PUSH_MAX || len(batches[2]) != 500 {
+		t.Fatalf("batch sizes = %d", len(batches))
+	}
+	cids := map[string]bool{}
+	for _, batch := range batches {
+		for cid, item := range batch {
+			if cids[cid] {
+				t.Fatalf("cid %s is used twice", cid)
+			}
+			cids[cid] = true
+			if want := payloads[item.Target].Notification.Alert; item.Notification == nil || item.Notification.Alert != want {
+				t.Fatalf("target %s got %+v, want alert %s", item.Target, item.Notification, want)
+			}
+		}
+	}
+	if len(cids) != len(payloads) {
+		t.Fatalf("%d cids for %d targets", len(cids), len(payloads))
+	}
+
+	// per target results are merged across the batches
+	if len(ret) != len(payloads) {
+		t.Fatalf("%d results for %d targets", len(ret), len(payloads))
+	}
+	var apiErr *APIError
+	if r := ret["u0001"]; !errors.As(r.Error, &apiErr) || apiErr.Code != 1011 || r.MsgID != 0 {
+		t.Fatalf("u0001 = %+v, want the per target error", r)
+	}
+	if r := ret["u0002"]; r.Error == nil {
+		t.Fatalf("u0002 = %+v, a missing result should be an error", r)
+	}
+	if r := ret["u0003"]; r.Error != nil || r.MsgID != 3 {
+		t.Fatalf("u0003 = %+v", r)
+	}
+	if r := ret["u1500"]; !errors.As(r.Error, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("u1500 = %+v, want the error of its batch", r)
+	}
+	if r := ret["u2499"]; r.Error != nil || r.MsgID != 2499 {
+		t.Fatalf("u2499 = %+v", r)
+	}
+
+	if _, err := c.BatchPush("tag", payloads); err == nil {
+		t.Fatal("an invalid target type should be rejected")
+	}
+	if _, err := c.BatchPush(BATCH_REGID, map[string]*PayLoad{"": NewPayLoad()}); err == nil {
+		t.Fatal("an empty target should be rejected")
+	}
+}
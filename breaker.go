@@ -0,0 +1,281 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIFamily JPush 接口类别，熔断等按类别统计
+type APIFamily string
+
+const (
+	API_PUSH     APIFamily = "push"     // 推送、cid、撤销等 api.jpush.cn/v3/push 接口
+	API_SCHEDULE APIFamily = "schedule" // 定时任务接口
+	API_REPORT   APIFamily = "report"   // 统计接口
+	API_DEVICE   APIFamily = "device"   // 设备接口
+	API_SMS      APIFamily = "sms"      // 短信接口
+)
+
+// apiFamilies lists the families guarded by the circuit breaker
+var apiFamilies = []APIFamily{API_PUSH, API_SCHEDULE, API_REPORT, API_DEVICE, API_SMS}
+
+// apiFamilyOf returns the family of a request url, or "" when it belongs to none
+func apiFamilyOf(u *url.URL) APIFamily {
+	host := u.Hostname()
+	switch {
+	case strings.HasPrefix(host, "report."):
+		return API_REPORT
+	case strings.HasPrefix(host, "device."):
+		return API_DEVICE
+	case strings.Contains(host, ".sms."):
+		return API_SMS
+	case strings.HasPrefix(u.Path, "/v3/schedules"):
+		return API_SCHEDULE
+	case strings.HasPrefix(u.Path, "/v3/push"):
+		return API_PUSH
+	}
+	return ""
+}
+
+type BreakerState int
+
+const (
+	BREAKER_CLOSED    BreakerState = iota // 正常放行请求
+	BREAKER_OPEN                          // 熔断中，请求直接失败
+	BREAKER_HALF_OPEN                     // 放行少量探测请求，成功后恢复
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BREAKER_CLOSED:
+		return "closed"
+	case BREAKER_OPEN:
+		return "open"
+	case BREAKER_HALF_OPEN:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+var ErrCircuitOpen = errors.New("jpush: circuit breaker is open")
+
+// CircuitOpenError 熔断期间请求直接失败时返回的错误，errors.Is(err, ErrCircuitOpen) 为 true
+type CircuitOpenError struct {
+	Family     APIFamily     // 熔断的接口类别
+	RetryAfter time.Duration // 距离进入半开状态的时长
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("jpush: circuit breaker of %s api is open, retry after %s", e.Family, e.RetryAfter)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerOptions 熔断参数，零值字段使用默认值
+type BreakerOptions struct {
+	Window         time.Duration // 统计失败率的时间窗口，默认 30 秒
+	MinRequests    int           // 窗口内请求数达到该值后才判断失败率，默认 20
+	FailureRatio   float64       // 窗口内失败率达到该值时熔断，范围 (0, 1]，默认 0.5
+	OpenTimeout    time.Duration // 熔断后经过该时长进入半开状态，默认 30 秒
+	HalfOpenProbes int           // 半开状态放行的探测请求数，全部成功后恢复，默认 1
+}
+
+// BreakerStatus 熔断器状态，用于健康检查
+type BreakerStatus struct {
+	State    BreakerState // 当前状态
+	Requests int          // 当前窗口内的请求数
+	Failures int          // 当前窗口内的失败数
+	OpenedAt time.Time    // 最近一次熔断的时间
+}
+
+// EnableCircuitBreaker 为客户端按接口类别（push、schedule、report、device、sms）启用熔断。
+// 连接错误、超时与 5xx 响应计为失败，失败率达到阈值后该类别的请求直接返回 *CircuitOpenError，
+// 经过 OpenTimeout 后放行探测请求，探测成功则恢复。需要在发送请求前调用。
+func (j *JPushClient) EnableCircuitBreaker(opts *BreakerOptions) {
+	j.breakers = newBreakerGroup(opts)
+}
+
+// Breakers 返回各接口类别的熔断状态，未启用熔断时返回 nil
+func (j *JPushClient) Breakers() map[APIFamily]BreakerStatus {
+	if j.breakers == nil {
+		return nil
+	}
+
+	ret := make(map[APIFamily]BreakerStatus, len(j.breakers))
+	for family, b := range j.breakers {
+		ret[family] = b.status()
+	}
+	return ret
+}
+
+// breakerGroup holds one breaker per api family
+type breakerGroup map[APIFamily]*circuitBreaker
+
+func newBreakerGroup(opts *BreakerOptions) breakerGroup {
+	o := BreakerOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Window <= 0 {
+		o.Window = 30 * time.Second
+	}
+	if o.MinRequests <= 0 {
+		o.MinRequests = 20
+	}
+	if o.FailureRatio <= 0 || o.FailureRatio > 1 {
+		o.FailureRatio = 0.5
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = 30 * time.Second
+	}
+	if o.HalfOpenProbes <= 0 {
+		o.HalfOpenProbes = 1
+	}
+
+	g := make(breakerGroup, len(apiFamilies))
+	for _, family := range apiFamilies {
+		g[family] = &circuitBreaker{family: family, opts: o}
+	}
+	return g
+}
+
+// wrap guards a transport with the breaker of each request's family
+func (g breakerGroup) wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b := g[apiFamilyOf(req.URL)]
+		if b == nil {
+			return next.RoundTrip(req)
+		}
+
+		gen, err := b.allow()
+		if err != nil {
+			// a RoundTripper closes the body even when it fails, this also ends multipart writers
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		resp, err := next.RoundTrip(req)
+		b.record(gen, !isOutage(resp, err))
+		return resp, err
+	})
+}
+
+// isOutage tells whether the outcome of a request points at JPush being unavailable
+func isOutage(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type circuitBreaker struct {
+	family APIFamily
+	opts   BreakerOptions
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	generation  uint64 // changes with every state, results of earlier states are not counted
+	probes      int    // probes in flight while half-open
+	successes   int    // successful probes while half-open
+}
+
+// allow reports whether a request may be sent now and returns the generation its result is counted in
+func (b *circuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BREAKER_OPEN:
+		wait := b.opts.OpenTimeout - time.Since(b.openedAt)
+		if wait > 0 {
+			return 0, &CircuitOpenError{Family: b.family, RetryAfter: wait}
+		}
+		b.setState(BREAKER_HALF_OPEN)
+		b.probes, b.successes = 0, 0
+		fallthrough
+	case BREAKER_HALF_OPEN:
+		if b.probes >= b.opts.HalfOpenProbes {
+			return 0, &CircuitOpenError{Family: b.family}
+		}
+		b.probes++
+	}
+	return b.generation, nil
+}
+
+// record counts the outcome of a request allowed in generation gen, requests
+// allowed before the last state change are ignored
+func (b *circuitBreaker) record(gen uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if gen != b.generation {
+		return
+	}
+	now := time.Now()
+	switch b.state {
+	case BREAKER_CLOSED:
+		if now.Sub(b.windowStart) >= b.opts.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if !success {
+			b.failures++
+		}
+		if b.requests >= b.opts.MinRequests && float64(b.failures) >= b.opts.FailureRatio*float64(b.requests) {
+			b.trip(now)
+		}
+	case BREAKER_HALF_OPEN:
+		b.probes--
+		if !success {
+			b.trip(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenProbes {
+			b.setState(BREAKER_CLOSED)
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	}
+}
+
+// trip opens the breaker, b.mu must be held
+func (b *circuitBreaker) trip(now time.Time) {
+	b.setState(BREAKER_OPEN)
+	b.openedAt = now
+}
+
+// setState moves the breaker to state and starts a new generation, b.mu must be held
+func (b *circuitBreaker) setState(state BreakerState) {
+	b.state = state
+	b.generation++
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.state
+	if state == BREAKER_OPEN && time.Since(b.openedAt) >= b.opts.OpenTimeout {
+		state = BREAKER_HALF_OPEN
+	}
+	return BreakerStatus{State: state, Requests: b.requests, Failures: b.failures, OpenedAt: b.openedAt}
+}
@@ -0,0 +1,168 @@
+package jpush
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubResponse returns a response to req with status and body
+func stubResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}
+}
+
+// stubTransport answers every request with status and body, record is called with each request first and may be nil
+func stubTransport(status int, body string, record func(*http.Request)) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if record != nil {
+			record(req)
+		}
+		return stubResponse(req, status, body), nil
+	})
+}
+
+func TestAPIFamilyOf(t *testing.T) {
+	cases := map[string]APIFamily{
+		HOST_PUSH:                              API_PUSH,
+		HOST_CID:                               API_PUSH,
+		HOST_PUSH + "/1/withdraw":              API_PUSH,
+		HOST_SCHEDULE + "/abc":                 API_SCHEDULE,
+		HOST_REPORT:                            API_REPORT,
+		HOST_REPORT_MESSAGES_DETAIL:            API_REPORT,
+		SMS:                                    API_SMS,
+		"https://device.jpush.cn/v3/devices/x": API_DEVICE,
+		HOST_IMAGES:                            "",
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse(raw)
+		if got := apiFamilyOf(u); got != want {
+			t.Errorf("apiFamilyOf(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var hits, healthy atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if healthy.Load() == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"sendno":"0","msg_id":"1"}`))
+	}))
+	defer srv.Close()
+
+	c := NewJPushClient("key", "secret")
+	c.EnableCircuitBreaker(&BreakerOptions{MinRequests: 2, FailureRatio: 0.5, OpenTimeout: 50 * time.Millisecond})
+	push := func() error {
+		return sendJSON(c.newRequest(http.MethodPost, srv.URL+"/v3/push").SetBody([]byte("{}")), nil)
+	}
+
+	for i := 0; i < 2; i++ {
+		var apiErr *APIError
+		if err := push(); !errors.As(err, &apiErr) {
+			t.Fatalf("push %d: err = %v, want *APIError", i, err)
+		}
+	}
+	if st := c.Breakers()[API_PUSH].State; st != BREAKER_OPEN {
+		t.Fatalf("state = %s, want open", st)
+	}
+
+	err := push()
+	var open *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &open) || open.Family != API_PUSH {
+		t.Fatalf("err = %v, want *CircuitOpenError for push", err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("server hit %d times while open, want 2", hits.Load())
+	}
+	if st := c.Breakers()[API_SCHEDULE].State; st != BREAKER_CLOSED {
+		t.Fatalf("schedule state = %s, want closed", st)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if st := c.Breakers()[API_PUSH].State; st != BREAKER_HALF_OPEN {
+		t.Fatalf("state = %s, want half-open", st)
+	}
+	healthy.Store(1)
+	if err := push(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if st := c.Breakers()[API_PUSH].State; st != BREAKER_CLOSED {
+		t.Fatalf("state = %s, want closed after a successful probe", st)
+	}
+}
+
+func TestCircuitBreakerCountsOnlyProbesWhileHalfOpen(t *testing.T) {
+	g := newBreakerGroup(&BreakerOptions{MinRequests: 1, OpenTimeout: time.Millisecond, HalfOpenProbes: 2})
+	b := g[API_PUSH]
+
+	slow, err := b.allow() // admitted while closed, finishes after the breaker trips
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed, _ := b.allow()
+	b.record(failed, false)
+	time.Sleep(2 * time.Millisecond)
+
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	b.record(slow, true)
+	b.record(slow, true)
+	b.mu.Lock()
+	state, probes, successes := b.state, b.probes, b.successes
+	b.mu.Unlock()
+	if state != BREAKER_HALF_OPEN || probes != 1 || successes != 0 {
+		t.Fatalf("state = %s, probes = %d, successes = %d: results admitted while closed should not count", state, probes, successes)
+	}
+
+	b.record(probe, true)
+	second, err := b.allow()
+	if err != nil {
+		t.Fatalf("second probe: %v", err)
+	}
+	b.record(second, true)
+	if st := b.status().State; st != BREAKER_CLOSED {
+		t.Fatalf("state = %s, want closed after the probes succeed", st)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCircuitBreakerClosesBodyWhenOpen(t *testing.T) {
+	g := newBreakerGroup(&BreakerOptions{MinRequests: 1, OpenTimeout: time.Hour})
+	rt := g.wrap(stubTransport(http.StatusServiceUnavailable, "", nil))
+
+	req, _ := http.NewRequest(http.MethodPost, HOST_PUSH, strings.NewReader("{}"))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	body := &closeRecorder{Reader: strings.NewReader("{}")}
+	req, _ = http.NewRequest(http.MethodPost, HOST_PUSH, body)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if !body.closed {
+		t.Fatal("the request body should be closed when the breaker fails fast")
+	}
+}
@@ -1,8 +1,9 @@
 package jpush
 
 import (
-	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
 )
 
 type CidRequest struct {
@@ -11,6 +12,7 @@ type CidRequest struct {
 }
 
 type CidResponse struct {
+	ResponseTiming
 	CidList []string `json:"cidlist,omitempty"` // CID 列表
 }
 
@@ -42,17 +44,30 @@ func NewCidRequest(count int, pushType string) *CidRequest {
 
 // GetCidList 获取 CID 列表
 func (c *CidRequest) GetCidList(key, secret string) (*CidResponse, error) {
-	resp := &CidResponse{}
-	jc := NewJPushClient(key, secret)
-
-	data, err := jc.GetCid(c.Count, c.Type)
+	cids, err := NewJPushClient(key, secret).getCidList(c.Count, c.Type)
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("%+v\n", string(data))
+	return &CidResponse{CidList: cids}, nil
+}
+
+// GetCidList 获取 CID 列表，count 范围为 [1, 1000]，pushType 为 push 或 schedule
+func (j *JPushClient) GetCidList(count int, pushType string) ([]string, error) {
+	return j.getCidList(count, pushType)
+}
 
-	err = json.Unmarshal(data, resp)
+// getCidList requests count cids of pushType and returns the typed list
+func (j *JPushClient) getCidList(count int, pushType string) ([]string, error) {
+	r := NewCidRequest(count, pushType)
 
-	return resp, err
+	req := j.newRequest(http.MethodGet, HOST_CID)
+	req.SetQueryParam("count", strconv.Itoa(r.Count))
+	req.SetQueryParam("type", r.Type)
+
+	resp := &CidResponse{}
+	if err := sendJSON(req, resp); err != nil {
+		return nil, err
+	}
+	return resp.CidList, nil
 }
@@ -0,0 +1,201 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+const (
+	CID_TYPE_PUSH     = "push"     // 推送使用的 cid
+	CID_TYPE_SCHEDULE = "schedule" // 定时任务使用的 cid
+
+	CID_MAX_COUNT = 1000 // 单次最多获取的 cid 数量
+)
+
+// CidPoolOptions cid 池参数，零值字段使用默认值
+type CidPoolOptions struct {
+	BatchSize int  // 每次预取的 cid 数量，默认且最大为 1000
+	LowWater  int  // 剩余 cid 少于该值时在后台补充，默认为 BatchSize 的 1/4
+	Prefetch  bool // 创建时是否立即在后台预取 push 与 schedule 两种 cid
+}
+
+// CidPool 并发安全的 cid 池，按类型（push/schedule）批量预取 cid，每个 cid 只会被取出一次
+type CidPool struct {
+	batchSize int
+	lowWater  int
+	fetch     func(count int, pushType string) ([]string, error)
+
+	mu     sync.Mutex
+	queues map[string]*cidQueue
+}
+
+type cidQueue struct {
+	cids    []string
+	filling bool
+	gen     int // incremented after every fill attempt
+	err     error
+	cond    *sync.Cond
+}
+
+// NewCidPool 创建 cid 池
+func NewCidPool(j *JPushClient, opts *CidPoolOptions) *CidPool {
+	o := CidPoolOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.BatchSize <= 0 || o.BatchSize > CID_MAX_COUNT {
+		o.BatchSize = CID_MAX_COUNT
+	}
+	if o.LowWater <= 0 || o.LowWater >= o.BatchSize {
+		o.LowWater = o.BatchSize / 4
+	}
+
+	p := &CidPool{
+		batchSize: o.BatchSize,
+		lowWater:  o.LowWater,
+		fetch:     j.getCidList,
+		queues:    make(map[string]*cidQueue),
+	}
+
+	if o.Prefetch {
+		for _, t := range []string{CID_TYPE_PUSH, CID_TYPE_SCHEDULE} {
+			p.mu.Lock()
+			q := p.queue(t)
+			q.filling = true
+			p.mu.Unlock()
+			go p.fill(t, q)
+		}
+	}
+
+	return p
+}
+
+// EnableCidPool 为客户端启用 cid 池，Push 与 CreateSchedule 会为未设置 cid 的请求自动填充 cid。
+// 需要在发送请求前调用。
+func (j *JPushClient) EnableCidPool(opts *CidPoolOptions) *CidPool {
+	j.cidPool = NewCidPool(j, opts)
+	return j.cidPool
+}
+
+// Get 取出一个指定类型的 cid，池为空时同步获取一批
+func (p *CidPool) Get(pushType string) (string, error) {
+	if pushType != CID_TYPE_PUSH && pushType != CID_TYPE_SCHEDULE {
+		return "", errors.New("invalid cid type")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	q := p.queue(pushType)
+	for len(q.cids) == 0 {
+		if !q.filling {
+			q.filling = true
+			p.mu.Unlock()
+			p.fill(pushType, q)
+			p.mu.Lock()
+			if len(q.cids) == 0 && q.err != nil {
+				return "", q.err
+			}
+			continue
+		}
+
+		gen := q.gen
+		for q.gen == gen {
+			q.cond.Wait()
+		}
+		if len(q.cids) == 0 && q.err != nil {
+			return "", q.err
+		}
+	}
+
+	cid := q.cids[0]
+	q.cids = q.cids[1:]
+
+	if len(q.cids) < p.lowWater && !q.filling {
+		q.filling = true
+		go p.fill(pushType, q)
+	}
+
+	return cid, nil
+}
+
+// Len 返回指定类型剩余的 cid 数量
+func (p *CidPool) Len(pushType string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.queue(pushType).cids)
+}
+
+// queue returns the queue of pushType, p.mu must be held
+func (p *CidPool) queue(pushType string) *cidQueue {
+	q, ok := p.queues[pushType]
+	if !ok {
+		q = &cidQueue{cond: sync.NewCond(&p.mu)}
+		p.queues[pushType] = q
+	}
+	return q
+}
+
+// fill fetches one batch of cids into q, q.filling must have been set by the caller
+func (p *CidPool) fill(pushType string, q *cidQueue) {
+	cids, err := p.fetch(p.batchSize, pushType)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	q.cids = append(q.cids, cids...)
+	q.err = err
+	if err == nil && len(cids) == 0 {
+		q.err = errors.New("empty cid list")
+	}
+	q.filling = false
+	q.gen++
+	q.cond.Broadcast()
+}
+
+// withCid sets a pooled cid into the json body when the client has a cid pool and the body has no cid
+func (j *JPushClient) withCid(data []byte, pushType string) ([]byte, error) {
+	if j.cidPool == nil {
+		return data, nil
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+
+	if raw, ok := body["cid"]; ok {
+		var cid string
+		if err := json.Unmarshal(raw, &cid); err == nil && cid != "" {
+			return data, nil
+		}
+	}
+
+	cid, err := j.cidPool.Get(pushType)
+	if err != nil {
+		return nil, err
+	}
+
+	body["cid"], err = json.Marshal(cid)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(body)
+}
+
+// setCid replaces the cid of the json body
+func setCid(data []byte, cid string) ([]byte, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+
+	var err error
+	body["cid"], err = json.Marshal(cid)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(body)
+}
@@ -0,0 +1,88 @@
+package jpush
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestCidPool(batch int) (*CidPool, *int64) {
+	var next int64
+	p := NewCidPool(NewJPushClient("appKey", "masterSecret"), &CidPoolOptions{BatchSize: batch})
+	p.fetch = func(count int, pushType string) ([]string, error) {
+		cids := make([]string, count)
+		for i := range cids {
+			cids[i] = fmt.Sprintf("%s-%d", pushType, atomic.AddInt64(&next, 1))
+		}
+		return cids, nil
+	}
+	return p, &next
+}
+
+func TestCidPoolUnique(t *testing.T) {
+	p, _ := newTestCidPool(64)
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				cid, err := p.Get(CID_TYPE_PUSH)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				if seen[cid] {
+					t.Errorf("duplicate cid %s", cid)
+				}
+				seen[cid] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != 2000 {
+		t.Errorf("got %d cids, want 2000", len(seen))
+	}
+}
+
+func TestCidPoolError(t *testing.T) {
+	p, _ := newTestCidPool(10)
+	p.fetch = func(int, string) ([]string, error) { return nil, fmt.Errorf("boom") }
+	if _, err := p.Get(CID_TYPE_SCHEDULE); err == nil {
+		t.Error("expected fetch error")
+	}
+	if _, err := p.Get("unknown"); err == nil {
+		t.Error("expected invalid type error")
+	}
+}
+
+func TestWithCid(t *testing.T) {
+	j := NewJPushClient("appKey", "masterSecret")
+	p, _ := newTestCidPool(10)
+	j.cidPool = p
+
+	data, err := j.withCid([]byte(`{"platform":"all","cid":""}`), CID_TYPE_SCHEDULE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["cid"] != "schedule-1" {
+		t.Errorf("cid = %v", body["cid"])
+	}
+
+	data, err = j.withCid([]byte(`{"platform":"all","cid":"mine"}`), CID_TYPE_PUSH)
+	if err != nil || string(data) != `{"platform":"all","cid":"mine"}` {
+		t.Errorf("existing cid must be kept, got %s %v", data, err)
+	}
+}
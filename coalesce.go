@@ -0,0 +1,216 @@
+package jpush
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CoalesceOptions 推送合并参数，零值字段使用默认值
+type CoalesceOptions struct {
+	Window     time.Duration // 合并窗口，从第一条推送到达开始计时，默认 10ms
+	MaxTargets int           // 合并后的最大推送目标数量，默认且最大为 1000
+}
+
+// Coalescer 将短时间内除推送目标外完全相同的推送合并为一次请求。
+// 仅合并推送目标为单一别名或注册 ID 且未设置 cid 的推送，其它推送直接发送。
+type Coalescer struct {
+	window     time.Duration
+	maxTargets int
+	send       func(ctx context.Context, data []byte) (string, error)
+
+	mu     sync.Mutex
+	groups map[string]*coalesceGroup
+}
+
+type coalesceResult struct {
+	body string
+	err  error
+}
+
+// coalesceGroup collects the pushes merged into one request
+type coalesceGroup struct {
+	key          string
+	lane         string
+	body         map[string]json.RawMessage // the payload without audience
+	audienceType AudienceType
+	targets      []string
+	seen         map[string]bool
+	waiters      []chan coalesceResult
+	timer        *time.Timer
+}
+
+// EnableCoalescing 为客户端启用推送合并，之后 Push 会在合并窗口内合并相同内容的推送，
+// 每个调用方都会收到合并请求的返回结果。需要在发送请求前调用。
+func (j *JPushClient) EnableCoalescing(opts *CoalesceOptions) *Coalescer {
+	j.coalescer = newCoalescer(j.push, opts)
+	return j.coalescer
+}
+
+func newCoalescer(send func(context.Context, []byte) (string, error), opts *CoalesceOptions) *Coalescer {
+	o := CoalesceOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Window <= 0 {
+		o.Window = 10 * time.Millisecond
+	}
+	if o.MaxTargets <= 0 || o.MaxTargets > AUDIENCE_MAX_TARGETS {
+		o.MaxTargets = AUDIENCE_MAX_TARGETS
+	}
+
+	return &Coalescer{
+		window:     o.Window,
+		maxTargets: o.MaxTargets,
+		send:       send,
+		groups:     make(map[string]*coalesceGroup),
+	}
+}
+
+// push sends data alone or merged with other pushes of the same content and waits for the result
+func (c *Coalescer) push(ctx context.Context, data []byte) (string, error) {
+	key, body, audienceType, targets, ok := coalesceKey(data)
+	if !ok || len(targets) > c.maxTargets {
+		return c.send(ctx, data)
+	}
+
+	// pushes of different priority lanes are never merged
+	lane := laneFromContext(ctx)
+	wait := make(chan coalesceResult, 1)
+	c.add(lane, lane+"|"+key, body, audienceType, targets, wait)
+
+	select {
+	case r := <-wait:
+		return r.body, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// add puts the targets into the open group of key, flushing the group first when they do not fit
+func (c *Coalescer) add(lane, key string, body map[string]json.RawMessage, audienceType AudienceType, targets []string, wait chan coalesceResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g := c.groups[key]
+	if g != nil && len(g.targets)+g.missing(targets) > c.maxTargets {
+		c.flushLocked(g)
+		g = nil
+	}
+	if g == nil {
+		g = &coalesceGroup{key: key, lane: lane, body: body, audienceType: audienceType, seen: make(map[string]bool)}
+		c.groups[key] = g
+		g.timer = time.AfterFunc(c.window, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.flushLocked(g)
+		})
+	}
+
+	for _, t := range targets {
+		if !g.seen[t] {
+			g.seen[t] = true
+			g.targets = append(g.targets, t)
+		}
+	}
+	g.waiters = append(g.waiters, wait)
+
+	if len(g.targets) >= c.maxTargets {
+		c.flushLocked(g)
+	}
+}
+
+// flushLocked closes the group and sends it in the background, c.mu must be held
+func (c *Coalescer) flushLocked(g *coalesceGroup) {
+	if c.groups[g.key] != g {
+		return // already flushed
+	}
+	delete(c.groups, g.key)
+	g.timer.Stop()
+
+	go func() {
+		r := coalesceResult{}
+		data, err := g.request()
+		if err != nil {
+			r.err = err
+		} else {
+			r.body, r.err = c.send(WithLane(context.Background(), g.lane), data)
+		}
+
+		for _, w := range g.waiters {
+			w <- r
+		}
+	}()
+}
+
+// missing counts the targets not yet in the group
+func (g *coalesceGroup) missing(targets []string) int {
+	n := 0
+	for _, t := range targets {
+		if !g.seen[t] {
+			n++
+		}
+	}
+	return n
+}
+
+// request builds the merged push body
+func (g *coalesceGroup) request() ([]byte, error) {
+	audience, err := json.Marshal(map[AudienceType][]string{g.audienceType: g.targets})
+	if err != nil {
+		return nil, err
+	}
+
+	body := make(map[string]json.RawMessage, len(g.body)+1)
+	for k, v := range g.body {
+		body[k] = v
+	}
+	body["audience"] = audience
+	return json.Marshal(body)
+}
+
+// coalesceKey tells whether data can be merged and returns the canonical content it is merged by
+func coalesceKey(data []byte) (key string, body map[string]json.RawMessage, audienceType AudienceType, targets []string, ok bool) {
+	if err := json.Unmarshal(data, &body); err != nil {
+		return "", nil, "", nil, false
+	}
+
+	if raw, has := body["cid"]; has {
+		var cid string
+		if json.Unmarshal(raw, &cid) != nil || cid != "" {
+			return "", nil, "", nil, false
+		}
+		delete(body, "cid")
+	}
+
+	var audience map[AudienceType][]string
+	if json.Unmarshal(body["audience"], &audience) != nil || len(audience) != 1 {
+		return "", nil, "", nil, false
+	}
+	for t, v := range audience {
+		audienceType, targets = t, v
+	}
+	if (audienceType != ALIAS && audienceType != REGISTRATION_ID) || len(targets) == 0 {
+		return "", nil, "", nil, false
+	}
+	delete(body, "audience")
+
+	// re-encode through generic values so that key order and spacing do not matter,
+	// numbers are kept as written so that large ids stay distinct
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return "", nil, "", nil, false
+	}
+	delete(m, "audience")
+	delete(m, "cid")
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", nil, "", nil, false
+	}
+
+	return string(audienceType) + ":" + string(b), body, audienceType, targets, true
+}
@@ -0,0 +1,147 @@
+package jpush
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type coalesceRecorder struct {
+	mu       sync.Mutex
+	requests []map[string]json.RawMessage
+}
+
+func (r *coalesceRecorder) send(ctx context.Context, data []byte) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return "", err
+	}
+	r.requests = append(r.requests, body)
+	return fmt.Sprintf(`{"sendno":"0","msg_id":"%d"}`, len(r.requests)), nil
+}
+
+func (r *coalesceRecorder) aliases(i int) []string {
+	var audience struct {
+		Alias []string `json:"alias"`
+	}
+	_ = json.Unmarshal(r.requests[i]["audience"], &audience)
+	sort.Strings(audience.Alias)
+	return audience.Alias
+}
+
+func coalescePayload(t *testing.T, alias, alert string) []byte {
+	t.Helper()
+
+	var pf Platform
+	pf.All()
+	var at Audience
+	at.SetAlias([]string{alias})
+	var n Notification
+	n.SetAlert(alert)
+
+	p := NewPayLoad()
+	p.SetPlatform(&pf)
+	p.SetAudience(&at)
+	p.SetNotification(&n)
+	data, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func pushAll(c *Coalescer, payloads [][]byte) []string {
+	bodies := make([]string, len(payloads))
+	var wg sync.WaitGroup
+	for i, data := range payloads {
+		wg.Add(1)
+		go func(i int, data []byte) {
+			defer wg.Done()
+			bodies[i], _ = c.push(context.Background(), data)
+		}(i, data)
+	}
+	wg.Wait()
+	return bodies
+}
+
+func TestCoalescerMergesAudience(t *testing.T) {
+	rec := &coalesceRecorder{}
+	c := newCoalescer(rec.send, &CoalesceOptions{Window: 50 * time.Millisecond})
+
+	bodies := pushAll(c, [][]byte{
+		coalescePayload(t, "a", "hello"),
+		coalescePayload(t, "b", "hello"),
+		coalescePayload(t, "b", "hello"),
+		coalescePayload(t, "c", "hello"),
+		coalescePayload(t, "d", "other"),
+	})
+
+	if len(rec.requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(rec.requests))
+	}
+	merged := 0
+	if len(rec.aliases(1)) == 3 {
+		merged = 1
+	}
+	if got := rec.aliases(merged); fmt.Sprint(got) != "[a b c]" {
+		t.Fatalf("merged aliases = %v", got)
+	}
+	if bodies[0] != bodies[1] || bodies[1] != bodies[2] || bodies[2] != bodies[3] {
+		t.Fatalf("merged callers got different results: %q", bodies)
+	}
+	if bodies[4] == bodies[0] {
+		t.Fatalf("different content was merged")
+	}
+}
+
+func TestCoalescerMaxTargets(t *testing.T) {
+	rec := &coalesceRecorder{}
+	c := newCoalescer(rec.send, &CoalesceOptions{Window: time.Hour, MaxTargets: 2})
+
+	done := make(chan []string)
+	go func() {
+		done <- pushAll(c, [][]byte{coalescePayload(t, "a", "x"), coalescePayload(t, "b", "x")})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a full group was not flushed before the window ended")
+	}
+	if len(rec.requests) != 1 || len(rec.aliases(0)) != 2 {
+		t.Fatalf("requests = %v", rec.requests)
+	}
+}
+
+func TestCoalesceKeySkipsCid(t *testing.T) {
+	data := coalescePayload(t, "a", "x")
+	if _, _, _, _, ok := coalesceKey(data); !ok {
+		t.Fatal("single alias push should be coalesced")
+	}
+
+	withCid, err := setCid(data, "8103a4c6-8c5f-4eb6-9a4d-2b3f2b2d3e6c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, _, ok := coalesceKey(withCid); ok {
+		t.Fatal("push with a cid must not be coalesced")
+	}
+}
+
+func TestCoalesceKeyKeepsLargeNumbers(t *testing.T) {
+	// both ids round to the same float64
+	a, _, _, _, ok := coalesceKey([]byte(`{"platform":"all","audience":{"alias":["a"]},"options":{"override_msg_id":9007199254740993}}`))
+	if !ok {
+		t.Fatal("single alias push should be coalesced")
+	}
+	b, _, _, _, _ := coalesceKey([]byte(`{"platform":"all","audience":{"alias":["b"]},"options":{"override_msg_id":9007199254740992}}`))
+	if a == b {
+		t.Fatal("pushes differing in a large number must not be merged")
+	}
+}
@@ -0,0 +1,199 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ENV_APP_KEY       = "JPUSH_APP_KEY"       // 默认保存 app key 的环境变量
+	ENV_MASTER_SECRET = "JPUSH_MASTER_SECRET" // 默认保存 master secret 的环境变量
+
+	redactedSecret = "******"
+)
+
+// Credentials 应用鉴权信息
+type Credentials struct {
+	AppKey       string `json:"app_key"`       // app key
+	MasterSecret string `json:"master_secret"` // master secret
+}
+
+func (c Credentials) String() string {
+	return fmt.Sprintf("Credentials{AppKey: %s, MasterSecret: %s}", c.AppKey, redactedSecret)
+}
+
+func (c Credentials) GoString() string {
+	return fmt.Sprintf("jpush.Credentials{AppKey:%q, MasterSecret:%q}", c.AppKey, redactedSecret)
+}
+
+// CredentialsProvider 提供应用鉴权信息，客户端在每个请求前调用，实现需要并发安全
+type CredentialsProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// StaticCredentials 固定的鉴权信息
+type StaticCredentials Credentials
+
+// Credentials 返回固定的鉴权信息
+func (s StaticCredentials) Credentials() (Credentials, error) {
+	if s.AppKey == "" || s.MasterSecret == "" {
+		return Credentials{}, errors.New("app key or master secret is empty")
+	}
+	return Credentials(s), nil
+}
+
+func (s StaticCredentials) String() string {
+	return Credentials(s).String()
+}
+
+func (s StaticCredentials) GoString() string {
+	return Credentials(s).GoString()
+}
+
+// EnvCredentials 每次从环境变量读取鉴权信息，变量名为空时使用 JPUSH_APP_KEY 与 JPUSH_MASTER_SECRET
+type EnvCredentials struct {
+	AppKeyVar       string // 保存 app key 的环境变量
+	MasterSecretVar string // 保存 master secret 的环境变量
+}
+
+// Credentials 从环境变量读取鉴权信息
+func (e EnvCredentials) Credentials() (Credentials, error) {
+	keyVar, secretVar := e.AppKeyVar, e.MasterSecretVar
+	if keyVar == "" {
+		keyVar = ENV_APP_KEY
+	}
+	if secretVar == "" {
+		secretVar = ENV_MASTER_SECRET
+	}
+
+	c := Credentials{AppKey: os.Getenv(keyVar), MasterSecret: os.Getenv(secretVar)}
+	if c.AppKey == "" || c.MasterSecret == "" {
+		return Credentials{}, fmt.Errorf("environment variable %s or %s is empty", keyVar, secretVar)
+	}
+	return c, nil
+}
+
+// FileCredentials 从 JSON 文件（{"app_key": "...", "master_secret": "..."}）读取鉴权信息，
+// 文件修改后自动重新加载，适用于定期轮换 master secret 的场景
+type FileCredentials struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	creds   Credentials
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// NewFileCredentials 创建文件鉴权信息，interval 为检查文件是否修改的最小间隔，小于等于 0 时为 5 秒。
+// 创建时会读取一次文件，读取失败时返回错误。
+func NewFileCredentials(path string, interval time.Duration) (*FileCredentials, error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	f := &FileCredentials{path: path, interval: interval}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Credentials 返回文件中的鉴权信息，距离上次检查超过 interval 时检查文件是否修改。
+// 文件暂时无法读取或内容无效时继续使用上一次加载的鉴权信息。
+func (f *FileCredentials) Credentials() (Credentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.checked) >= f.interval {
+		f.checked = time.Now()
+		if info, err := os.Stat(f.path); err == nil && (!info.ModTime().Equal(f.modTime) || info.Size() != f.size) {
+			_ = f.load()
+		}
+	}
+	return f.creds, nil
+}
+
+// Reload 立即重新加载文件
+func (f *FileCredentials) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.checked = time.Now()
+	return f.load()
+}
+
+func (f *FileCredentials) String() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return fmt.Sprintf("FileCredentials{Path: %s, AppKey: %s, MasterSecret: %s}", f.path, f.creds.AppKey, redactedSecret)
+}
+
+func (f *FileCredentials) GoString() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return fmt.Sprintf("&jpush.FileCredentials{Path:%q, AppKey:%q, MasterSecret:%q}", f.path, f.creds.AppKey, redactedSecret)
+}
+
+// load reads the file, f.mu must be held
+func (f *FileCredentials) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	var c Credentials
+	if err := json.Unmarshal(data, &c); err != nil {
+		return fmt.Errorf("invalid credentials file %s: %v", f.path, err)
+	}
+	c.AppKey = strings.TrimSpace(c.AppKey)
+	c.MasterSecret = strings.TrimSpace(c.MasterSecret)
+	if c.AppKey == "" || c.MasterSecret == "" {
+		return fmt.Errorf("credentials file %s has no app_key or master_secret", f.path)
+	}
+
+	f.creds = c
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return nil
+}
+
+// NewJPushClientWithCredentials 创建使用 CredentialsProvider 的客户端，每个请求前都会获取最新的鉴权信息
+func NewJPushClientWithCredentials(provider CredentialsProvider) *JPushClient {
+	return &JPushClient{credentials: provider}
+}
+
+// SetCredentialsProvider 设置鉴权信息来源，设置后 AppKey 与 MasterSecret 字段不再使用
+func (j *JPushClient) SetCredentialsProvider(provider CredentialsProvider) {
+	j.credentials = provider
+}
+
+// Credentials 返回客户端当前的鉴权信息
+func (j *JPushClient) Credentials() (Credentials, error) {
+	if j.credentials == nil {
+		return Credentials{AppKey: j.AppKey, MasterSecret: j.MasterSecret}, nil
+	}
+	return j.credentials.Credentials()
+}
+
+func (j *JPushClient) String() string {
+	c, _ := j.Credentials()
+	return fmt.Sprintf("JPushClient{AppKey: %s, MasterSecret: %s}", c.AppKey, redactedSecret)
+}
+
+func (j *JPushClient) GoString() string {
+	c, _ := j.Credentials()
+	return fmt.Sprintf("&jpush.JPushClient{AppKey:%q, MasterSecret:%q}", c.AppKey, redactedSecret)
+}
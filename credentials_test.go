@@ -0,0 +1,84 @@
+package jpush
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCredentialsProvider(t *testing.T) {
+	var users []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		users = append(users, user)
+		_, _ = w.Write([]byte(`{"cidlist":["c1"]}`))
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(file, []byte(`{"app_key":"k1","master_secret":"s1"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewFileCredentials(file, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewJPushClientWithCredentials(provider)
+	if _, err := c.newRequest(http.MethodGet, srv.URL+"/v3/push/cid").Bytes(); err != nil {
+		t.Fatal(err)
+	}
+
+	// rotated secrets are picked up by the next request, a broken file keeps the last ones
+	if err := os.WriteFile(file, []byte(`{"app_key":"k2","master_secret":"s2-rotated"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.newRequest(http.MethodGet, srv.URL+"/v3/push/cid").Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.newRequest(http.MethodGet, srv.URL+"/v3/push/cid").Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(users, ",") != "k1,k2,k2" {
+		t.Fatalf("users = %v, want k1,k2,k2", users)
+	}
+
+	creds, _ := provider.Credentials()
+	static := StaticCredentials(creds)
+	for _, v := range []interface{}{c, provider, creds, &creds, static, &static} {
+		for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+			s := fmt.Sprintf(verb, v)
+			if strings.Contains(s, "s2-rotated") || !strings.Contains(s, "k2") {
+				t.Fatalf("%s of %T = %q leaks the secret or misses the app key", verb, v, s)
+			}
+		}
+	}
+
+	t.Setenv("JPUSH_TEST_KEY", "k3")
+	t.Setenv("JPUSH_TEST_SECRET", "s3-env")
+	env := EnvCredentials{AppKeyVar: "JPUSH_TEST_KEY", MasterSecretVar: "JPUSH_TEST_SECRET"}
+	if creds, err := env.Credentials(); err != nil || creds.AppKey != "k3" {
+		t.Fatalf("env credentials = %v, %v", creds, err)
+	}
+	c.SetCredentialsProvider(env)
+	for _, v := range []interface{}{c, env, &env} {
+		for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+			if s := fmt.Sprintf(verb, v); strings.Contains(s, "s3-env") {
+				t.Fatalf("%s of %T = %q leaks the secret", verb, v, s)
+			}
+		}
+	}
+
+	c.SetCredentialsProvider(EnvCredentials{AppKeyVar: "JPUSH_TEST_NO_SUCH_KEY", MasterSecretVar: "JPUSH_TEST_NO_SUCH_SECRET"})
+	if _, err := c.newRequest(http.MethodGet, srv.URL+"/v3/push/cid").Bytes(); err == nil || len(users) != 3 {
+		t.Fatalf("err = %v, a request without credentials should fail before it is sent", err)
+	}
+}
@@ -0,0 +1,186 @@
+package jpush
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrDuplicatePush = errors.New("jpush: duplicate push suppressed")
+
+// DedupStore 推送去重记录的存储，实现需要并发安全
+type DedupStore interface {
+	// Add 记录 key，ttl 后过期。key 已存在且未过期时返回 false
+	Add(key string, ttl time.Duration) (bool, error)
+	// Remove 删除 key，推送失败时调用，使重试不被当作重复推送
+	Remove(key string) error
+}
+
+// DedupOptions 推送去重参数，零值字段使用默认值
+type DedupOptions struct {
+	Window time.Duration // 去重时间窗口，默认 1 分钟
+	Store  DedupStore    // 去重记录存储，默认为容量 10000 的内存 LRU
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey 返回携带幂等键的 ctx，启用去重后相同幂等键的推送只会发送一次
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// EnableDedup 为客户端启用推送去重，Push 会对推送内容的规范形式（不含 cid）计算哈希，
+// 时间窗口内重复的推送返回 ErrDuplicatePush 而不会发送。推送失败时会删除去重记录。
+// 需要在发送请求前调用。
+func (j *JPushClient) EnableDedup(opts *DedupOptions) {
+	j.dedup = newDeduper(j.pushCoalesced, opts)
+}
+
+// PushWithKey 使用调用方指定的幂等键推送消息，启用去重后相同幂等键的推送在时间窗口内只会发送一次
+func (j *JPushClient) PushWithKey(key string, data []byte) (string, error) {
+	return j.pushFront(WithIdempotencyKey(context.Background(), key), data)
+}
+
+type deduper struct {
+	window time.Duration
+	store  DedupStore
+	send   func(ctx context.Context, data []byte) (string, error)
+}
+
+func newDeduper(send func(context.Context, []byte) (string, error), opts *DedupOptions) *deduper {
+	o := DedupOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Window <= 0 {
+		o.Window = time.Minute
+	}
+	if o.Store == nil {
+		o.Store = NewMemoryDedupStore(10000)
+	}
+
+	return &deduper{window: o.Window, store: o.Store, send: send}
+}
+
+// push sends data unless a push with the same key was sent within the window
+func (d *deduper) push(ctx context.Context, data []byte) (string, error) {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	if key == "" {
+		var err error
+		if key, err = dedupHash(data); err != nil {
+			return "", err
+		}
+	}
+
+	added, err := d.store.Add(key, d.window)
+	if err != nil {
+		return "", err
+	}
+	if !added {
+		return "", ErrDuplicatePush
+	}
+
+	body, err := d.send(ctx, data)
+	if err != nil {
+		_ = d.store.Remove(key)
+	}
+	return body, err
+}
+
+// dedupHash hashes the canonical form of a push body, the cid is ignored.
+// Numbers are kept as written so that large ids do not collide after a float64 round trip.
+func dedupHash(data []byte) (string, error) {
+	var body map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&body); err != nil {
+		return "", err
+	}
+	delete(body, "cid")
+
+	// encoding/json sorts map keys, so equal payloads encode equally
+	canonical, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// MemoryDedupStore 基于内存的 LRU 去重存储，超过容量时淘汰最久未使用的记录
+type MemoryDedupStore struct {
+	capacity int
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List // front is the most recently used
+}
+
+type dedupItem struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryDedupStore 创建内存去重存储，capacity 小于等于 0 时使用 10000
+func NewMemoryDedupStore(capacity int) *MemoryDedupStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &MemoryDedupStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Add 记录 key，key 已存在且未过期时返回 false
+func (s *MemoryDedupStore) Add(key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := s.items[key]; ok {
+		item := e.Value.(*dedupItem)
+		if now.Before(item.expires) {
+			s.lru.MoveToFront(e)
+			return false, nil
+		}
+		item.expires = now.Add(ttl)
+		s.lru.MoveToFront(e)
+		return true, nil
+	}
+
+	s.items[key] = s.lru.PushFront(&dedupItem{key: key, expires: now.Add(ttl)})
+	for s.lru.Len() > s.capacity {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.items, oldest.Value.(*dedupItem).key)
+	}
+	return true, nil
+}
+
+// Remove 删除 key
+func (s *MemoryDedupStore) Remove(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.items[key]; ok {
+		s.lru.Remove(e)
+		delete(s.items, key)
+	}
+	return nil
+}
+
+// Len 返回记录数量，包含已过期但尚未淘汰的记录
+func (s *MemoryDedupStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
@@ -0,0 +1,103 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeduperSuppressesDuplicates(t *testing.T) {
+	sent := 0
+	fail := false
+	send := func(ctx context.Context, data []byte) (string, error) {
+		sent++
+		if fail {
+			return "", errors.New("unavailable")
+		}
+		return `{"msg_id":"1"}`, nil
+	}
+	d := newDeduper(send, &DedupOptions{Window: time.Hour})
+	ctx := context.Background()
+
+	data := coalescePayload(t, "a", "hello")
+	if _, err := d.push(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	// the cid does not take part in the hash
+	withCid, _ := setCid(data, "another-cid")
+	if _, err := d.push(ctx, withCid); err != ErrDuplicatePush {
+		t.Fatalf("err = %v, want ErrDuplicatePush", err)
+	}
+	if _, err := d.push(ctx, coalescePayload(t, "b", "hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed push can be retried
+	fail = true
+	other := coalescePayload(t, "c", "hello")
+	if _, err := d.push(ctx, other); err == nil || err == ErrDuplicatePush {
+		t.Fatalf("err = %v, want the send error", err)
+	}
+	fail = false
+	if _, err := d.push(ctx, other); err != nil {
+		t.Fatalf("retry after failure: %v", err)
+	}
+
+	// idempotency keys override the content hash
+	keyed := WithIdempotencyKey(ctx, "order-42")
+	if _, err := d.push(keyed, coalescePayload(t, "d", "x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.push(keyed, coalescePayload(t, "e", "y")); err != ErrDuplicatePush {
+		t.Fatalf("err = %v, want ErrDuplicatePush for a repeated key", err)
+	}
+
+	if sent != 5 {
+		t.Fatalf("sent %d pushes, want 5", sent)
+	}
+}
+
+func TestMemoryDedupStore(t *testing.T) {
+	s := NewMemoryDedupStore(2)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if added, _ := s.Add(key, time.Hour); !added {
+			t.Fatalf("Add(%q) = false", key)
+		}
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", s.Len())
+	}
+	if added, _ := s.Add("a", time.Hour); !added {
+		t.Fatal("the least recently used key should have been evicted")
+	}
+
+	if added, _ := s.Add("x", -time.Second); !added {
+		t.Fatal("Add(x) = false")
+	}
+	if added, _ := s.Add("x", time.Hour); !added {
+		t.Fatal("an expired key should be added again")
+	}
+}
+
+func TestDedupHashKeepsLargeNumbers(t *testing.T) {
+	// both ids round to the same float64
+	a, err := dedupHash([]byte(`{"platform":"all","audience":"all","options":{"override_msg_id":9007199254740993}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := dedupHash([]byte(`{"platform":"all","audience":"all","options":{"override_msg_id":9007199254740992}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("pushes differing in a large number should not be duplicates")
+	}
+
+	// key order and spacing still do not matter
+	c, _ := dedupHash([]byte(`{ "options":{"override_msg_id":9007199254740993}, "audience":"all","platform":"all"}`))
+	if a != c {
+		t.Fatal("equal pushes should hash equally")
+	}
+}
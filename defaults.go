@@ -0,0 +1,198 @@
+package jpush
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Environment 推送环境，用于选择预设的默认推送选项
+type Environment string
+
+const (
+	ENVIRONMENT_DEVELOPMENT Environment = "development" // 开发环境，APNs 推送到开发环境
+	ENVIRONMENT_PRODUCTION  Environment = "production"  // 生产环境，APNs 推送到生产环境
+)
+
+// EnvironmentOptions 返回环境预设的推送选项
+func EnvironmentOptions(env Environment) (*Options, error) {
+	switch env {
+	case ENVIRONMENT_DEVELOPMENT:
+		o := &Options{}
+		o.SetApnsProduction(false)
+		return o, nil
+	case ENVIRONMENT_PRODUCTION:
+		o := &Options{}
+		o.SetApnsProduction(true)
+		return o, nil
+	}
+	return nil, fmt.Errorf("unknown environment %q", env)
+}
+
+// SetDefaultOptions 设置客户端的默认推送选项（包括厂商通道设置），发送时深度合并到每个推送的 options 中：
+// 推送 options 中出现的字段优先（包括显式设置的 apns_production: false 与 time_to_live: 0），
+// 未出现的字段使用默认值；third_party_channel 按厂商逐个字段合并，其中的零值字段视为未设置。
+// 对推送、批量单推、定时任务与地理围栏生效，需要在发送请求前调用。
+// 未设置默认推送选项时，带有 options 但没有 apns_production 的推送发送到 APNs 开发环境。
+func (j *JPushClient) SetDefaultOptions(opts *Options) error {
+	if opts == nil {
+		j.defaults = nil
+		return nil
+	}
+
+	defaults, err := decodeJSONObject(opts)
+	if err != nil {
+		return err
+	}
+	j.defaults = defaults
+	return nil
+}
+
+// SetEnvironment 使用环境预设的推送选项作为默认推送选项，opts 中设置的字段覆盖预设，可以为 nil
+func (j *JPushClient) SetEnvironment(env Environment, opts *Options) error {
+	preset, err := EnvironmentOptions(env)
+	if err != nil {
+		return err
+	}
+	if opts == nil {
+		return j.SetDefaultOptions(preset)
+	}
+
+	custom, err := decodeJSONObject(opts)
+	if err != nil {
+		return err
+	}
+	defaults, err := decodeJSONObject(preset)
+	if err != nil {
+		return err
+	}
+	j.defaults = mergeDefaults(custom, defaults, false).(map[string]interface{})
+	return nil
+}
+
+// sdkDefaults keep the sandbox default of the typed Options in the pushes that
+// are not merged with client default options
+var sdkDefaults = map[string]interface{}{"apns_production": false}
+
+// withDefaults merges the default options into the objects found at path of the
+// json body, "*" in path matches every value of an object. Without client default
+// options only the sandbox default is applied.
+func (j *JPushClient) withDefaults(data []byte, path ...string) ([]byte, error) {
+	if j.defaults == nil {
+		return withSandboxDefault(data, path...)
+	}
+	return mergeOptions(data, j.defaults, false, path)
+}
+
+// withSandboxDefault sets apns_production to false in the options found at path
+// that leave it out, bodies where every options has it are returned as is
+func withSandboxDefault(data []byte, path ...string) ([]byte, error) {
+	if bytes.Count(data, []byte(`"options"`)) <= bytes.Count(data, []byte(`"apns_production"`)) {
+		return data, nil
+	}
+	return mergeOptions(data, sdkDefaults, true, path)
+}
+
+// mergeOptions merges defaults into the options of the objects found at path,
+// with onlyPresent objects without options are left alone
+func mergeOptions(data []byte, defaults map[string]interface{}, onlyPresent bool, path []string) ([]byte, error) {
+	var body map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&body); err != nil {
+		return nil, err
+	}
+
+	for _, obj := range findObjects(body, path) {
+		if onlyPresent && obj["options"] == nil {
+			continue
+		}
+		obj["options"] = mergeDefaults(obj["options"], defaults, false)
+	}
+	return json.Marshal(body)
+}
+
+func findObjects(obj map[string]interface{}, path []string) []map[string]interface{} {
+	if len(path) == 0 {
+		return []map[string]interface{}{obj}
+	}
+
+	var children []interface{}
+	if path[0] == "*" {
+		for _, v := range obj {
+			children = append(children, v)
+		}
+	} else {
+		children = append(children, obj[path[0]])
+	}
+
+	var found []map[string]interface{}
+	for _, child := range children {
+		if m, ok := child.(map[string]interface{}); ok {
+			found = append(found, findObjects(m, path[1:])...)
+		}
+	}
+	return found
+}
+
+// mergeDefaults returns v with the keys it does not have filled from defaults,
+// objects are merged recursively. Keys that are present win even with a zero value,
+// except when zeroIsUnset is set: entries of third_party_channel always carry
+// channel_id and skip_quota, so their zero values are treated as missing.
+func mergeDefaults(v, defaults interface{}, zeroIsUnset bool) interface{} {
+	if v == nil || zeroIsUnset && isZeroJSON(v) {
+		return defaults
+	}
+
+	dm, ok := defaults.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	vm, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	merged := make(map[string]interface{}, len(vm)+len(dm))
+	for k, val := range vm {
+		merged[k] = val
+	}
+	for k, def := range dm {
+		merged[k] = mergeDefaults(merged[k], def, zeroIsUnset || k == "third_party_channel")
+	}
+	return merged
+}
+
+func isZeroJSON(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func decodeJSONObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
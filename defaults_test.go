@@ -0,0 +1,133 @@
+package jpush
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	var body []byte
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"sendno":"0","msg_id":"1"}`, func(req *http.Request) {
+		body, _ = io.ReadAll(req.Body)
+	}))
+
+	defaults := &Options{TimeToLive: 600}
+	defaults.AddThirdPartyChannel(XIAOMI, ThirdPartyOptions{ChannelId: "orders", Distribution: "secondary_push"})
+	if err := c.SetEnvironment(ENVIRONMENT_PRODUCTION, defaults); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPayLoad()
+	p.SetPlatform(&Platform{})
+	p.SetAudience(&Audience{})
+	p.SetNotification(&Notification{Alert: "hi"})
+	p.Options.SetTimeToLive(30)
+	p.Options.AddThirdPartyChannel(XIAOMI, ThirdPartyOptions{Distribution: "first_ossPush"})
+	data, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Push(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent struct {
+		Options Options `json:"options"`
+	}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		t.Fatal(err)
+	}
+	o := sent.Options
+	xiaomi := o.ThirdPartyChannel[XIAOMI.String()]
+	if !o.ApnsProduction || o.TimeToLive != 30 || xiaomi.ChannelId != "orders" || xiaomi.Distribution != "first_ossPush" {
+		t.Fatalf("sent options = %+v", o)
+	}
+
+	if err := c.SetEnvironment("staging", nil); err == nil {
+		t.Fatal("unknown environments should be rejected")
+	}
+}
+
+func TestDefaultOptionsExplicitOverrides(t *testing.T) {
+	var body []byte
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"sendno":"0","msg_id":"1"}`, func(req *http.Request) {
+		body, _ = io.ReadAll(req.Body)
+	}))
+	sentOptions := func() map[string]interface{} {
+		var sent struct {
+			Options map[string]interface{} `json:"options"`
+		}
+		if err := json.Unmarshal(body, &sent); err != nil {
+			t.Fatal(err)
+		}
+		return sent.Options
+	}
+
+	// without client defaults a typed payload keeps going to the sandbox
+	p := NewPayLoad()
+	p.SetPlatform(&Platform{})
+	p.SetAudience(&Audience{})
+	p.SetNotification(&Notification{Alert: "hi"})
+	data, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Push(data); err != nil {
+		t.Fatal(err)
+	}
+	if o := sentOptions(); o["apns_production"] != false {
+		t.Fatalf("sent options = %v, want the sandbox", o)
+	}
+
+	if err := c.SetEnvironment(ENVIRONMENT_PRODUCTION, &Options{TimeToLive: 600}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Push(data); err != nil {
+		t.Fatal(err)
+	}
+	if o := sentOptions(); o["apns_production"] != true || o["time_to_live"] != 600.0 {
+		t.Fatalf("sent options = %v, want the production defaults", o)
+	}
+
+	p.Options.SetApnsProduction(false)
+	p.Options.SetTimeToLive(0)
+	data, err = p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Push(data); err != nil {
+		t.Fatal(err)
+	}
+	if o := sentOptions(); o["apns_production"] != false || o["time_to_live"] != 0.0 {
+		t.Fatalf("sent options = %v, explicit values should win over the defaults", o)
+	}
+
+	if _, err := c.NewPush().IOS().ToAll().Alert("hi").ApnsProduction(false).Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if o := sentOptions(); o["apns_production"] != false || o["time_to_live"] != 600.0 {
+		t.Fatalf("sent options = %v, want the sandbox with the default ttl", o)
+	}
+
+	// raw payloads: present keys win
+	if _, err := c.Push([]byte(`{"platform":"all","audience":"all","notification":{"alert":"hi"},"options":{"apns_production":false,"time_to_live":0}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if o := sentOptions(); o["apns_production"] != false || o["time_to_live"] != 0.0 {
+		t.Fatalf("sent options = %v, explicit values should win over the defaults", o)
+	}
+
+	// explicit values survive a json round trip
+	var decoded Options
+	if err := json.Unmarshal([]byte(`{"apns_production":false,"time_to_live":0}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(decoded); string(data) != `{"time_to_live":0,"apns_production":false}` {
+		t.Fatalf("options = %s", data)
+	}
+}
@@ -0,0 +1,286 @@
+package jpush
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const dryRunMsgIDBase = 9000000000000000000 // fake msg ids count up from here
+
+// DryRunOptions 演练模式参数
+type DryRunOptions struct {
+	File string // 可选，以 JSONL 格式追加记录请求的文件路径
+}
+
+// DryRunRecord 演练模式下记录的一次请求
+type DryRunRecord struct {
+	Time       time.Time `json:"time"`            // 请求时间
+	Method     string    `json:"method"`          // 请求方法
+	URL        string    `json:"url"`             // 请求地址
+	Family     APIFamily `json:"family"`          // 接口类别
+	Body       string    `json:"body,omitempty"`  // 请求内容
+	StatusCode int       `json:"status_code"`     // 模拟的响应状态码
+	Response   string    `json:"response"`        // 模拟的响应内容
+	Error      string    `json:"error,omitempty"` // 校验失败的原因
+}
+
+// DryRunLog 演练模式下的请求记录
+type DryRunLog struct {
+	mu      sync.Mutex
+	records []DryRunRecord
+	file    *os.File
+	nextID  atomic.Int64
+}
+
+// Records 返回已记录的请求
+func (l *DryRunLog) Records() []DryRunRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]DryRunRecord(nil), l.records...)
+}
+
+// Reset 清空内存中的请求记录
+func (l *DryRunLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = nil
+}
+
+// Close 关闭记录文件
+func (l *DryRunLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// EnableDryRun 为客户端启用演练模式：请求经过完整的发送流程（cid、限流、熔断、中间件等），
+// 但不会发送到 JPush。每个请求会被校验并记录，返回带有模拟消息 ID 的响应，校验失败时返回 400 错误。
+// 需要在发送请求前调用。
+func (j *JPushClient) EnableDryRun(opts *DryRunOptions) (*DryRunLog, error) {
+	l := &DryRunLog{}
+	if opts != nil && opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		l.file = f
+	}
+	j.dryRun = l
+	return l, nil
+}
+
+// wrap replaces the transport, requests never leave the process
+func (l *DryRunLog) wrap(http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.Body != nil {
+			var err error
+			body, err = io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		rec := DryRunRecord{
+			Time:   time.Now(),
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Family: apiFamilyOf(req.URL),
+			Body:   string(body),
+		}
+
+		resp, err := l.respond(req, body)
+		if err != nil {
+			rec.StatusCode = http.StatusBadRequest
+			rec.Error = err.Error()
+			resp, _ = json.Marshal(map[string]interface{}{"error": map[string]interface{}{"code": 1003, "message": err.Error()}})
+		} else {
+			rec.StatusCode = http.StatusOK
+		}
+		rec.Response = string(resp)
+
+		if err := l.record(rec); err != nil {
+			return nil, err
+		}
+
+		return &http.Response{
+			Status:        http.StatusText(rec.StatusCode),
+			StatusCode:    rec.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{CONTENT_TYPE_JSON}},
+			Body:          io.NopCloser(bytes.NewReader(resp)),
+			ContentLength: int64(len(resp)),
+			Request:       req,
+		}, nil
+	})
+}
+
+func (l *DryRunLog) record(rec DryRunRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, rec)
+	if l.file == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+func (l *DryRunLog) msgID() string {
+	return strconv.FormatInt(dryRunMsgIDBase+l.nextID.Add(1), 10)
+}
+
+// respond validates the request and builds the synthetic response body of its endpoint
+func (l *DryRunLog) respond(req *http.Request, body []byte) ([]byte, error) {
+	path := req.URL.Path
+	query := req.URL.Query()
+
+	var obj map[string]json.RawMessage
+	if req.Method == http.MethodPost || req.Method == http.MethodPut {
+		if !strings.HasPrefix(req.Header.Get("Content-Type"), CONTENT_TYPE_JSON) {
+			// multipart uploads are recorded as they are
+			return json.Marshal(map[string]string{"media_id": "jgmedia-1-dryrun-" + l.msgID()})
+		}
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return nil, fmt.Errorf("invalid json body: %v", err)
+		}
+	}
+
+	switch {
+	case path == "/v3/push/cid":
+		count, _ := strconv.Atoi(query.Get("count"))
+		if count <= 0 {
+			count = 1
+		}
+		if count > CID_MAX_COUNT {
+			return nil, fmt.Errorf("count must not be more than %d", CID_MAX_COUNT)
+		}
+		cids := make([]string, count)
+		for i := range cids {
+			cids[i] = "dryrun-" + l.msgID()
+		}
+		return json.Marshal(map[string][]string{"cidlist": cids})
+
+	case path == "/v3/push" || path == "/v3/push/validate":
+		if err := validateDryRunPush(obj); err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]string{"sendno": "0", "msg_id": l.msgID()})
+
+	case strings.HasPrefix(path, "/v3/push/batch/"):
+		var list map[string]json.RawMessage
+		if err := json.Unmarshal(obj["pushlist"], &list); err != nil || len(list) == 0 {
+			return nil, errors.New("pushlist is empty")
+		}
+		if len(list) > BATCH_PUSH_MAX {
+			return nil, fmt.Errorf("pushlist has more than %d targets", BATCH_PUSH_MAX)
+		}
+		ret := make(map[string]map[string]string, len(list))
+		for cid := range list {
+			ret[cid] = map[string]string{"msg_id": l.msgID()}
+		}
+		return json.Marshal(ret)
+
+	case strings.HasPrefix(path, "/v3/push/") && strings.HasSuffix(path, "/withdraw"):
+		return []byte("{}"), nil
+
+	case path == "/v3/schedules" && req.Method == http.MethodPost:
+		for _, key := range []string{"name", "trigger", "push"} {
+			if len(obj[key]) == 0 {
+				return nil, fmt.Errorf("schedule %s is required", key)
+			}
+		}
+		var name string
+		_ = json.Unmarshal(obj["name"], &name)
+		return json.Marshal(map[string]string{"schedule_id": "dryrun-" + l.msgID(), "name": name})
+
+	case strings.HasPrefix(path, "/v3/schedules"):
+		return json.Marshal(map[string]interface{}{"schedule_id": query.Get("schedule_id"), "msg_ids": []string{}})
+
+	case path == "/v3/received" || path == "/v3/received/detail" || path == "/v3/messages/detail":
+		var ret []map[string]json.Number
+		for _, id := range strings.Split(query.Get("msg_ids"), ",") {
+			if id != "" {
+				ret = append(ret, map[string]json.Number{"msg_id": json.Number(id)})
+			}
+		}
+		if len(ret) == 0 {
+			return nil, errors.New("msg_ids is required")
+		}
+		return json.Marshal(ret)
+
+	case path == "/v3/users":
+		duration, _ := strconv.Atoi(query.Get("duration"))
+		return json.Marshal(map[string]interface{}{
+			"time_unit": query.Get("time_unit"),
+			"start":     query.Get("start"),
+			"duration":  duration,
+			"items":     []interface{}{},
+		})
+
+	case path == "/v1/messages":
+		if len(obj["mobile"]) == 0 {
+			return nil, errors.New("mobile is required")
+		}
+		return json.Marshal(map[string]string{"msg_id": l.msgID()})
+
+	case strings.HasPrefix(path, "/v3/images"):
+		return json.Marshal(map[string]string{"media_id": "jgmedia-1-dryrun-" + l.msgID()})
+
+	case strings.HasPrefix(path, "/v3/geofences") && req.Method == http.MethodPost:
+		return json.Marshal(map[string]string{"geofence_id": "dryrun-" + l.msgID()})
+	}
+
+	return []byte("{}"), nil
+}
+
+// validateDryRunPush checks the parts of a push body JPush requires
+func validateDryRunPush(obj map[string]json.RawMessage) error {
+	if len(obj["platform"]) == 0 {
+		return errors.New("platform is required")
+	}
+	if len(obj["audience"]) == 0 {
+		return errors.New("audience is required")
+	}
+	if len(obj["notification"]) == 0 && len(obj["message"]) == 0 && len(obj["live_activity"]) == 0 {
+		return errors.New("notification, message or live_activity is required")
+	}
+
+	var audience map[string]json.RawMessage
+	if json.Unmarshal(obj["audience"], &audience) == nil {
+		for key, raw := range audience {
+			var targets []string
+			if json.Unmarshal(raw, &targets) == nil && len(targets) > AUDIENCE_MAX_TARGETS {
+				return fmt.Errorf("audience %s has more than %d targets", key, AUDIENCE_MAX_TARGETS)
+			}
+		}
+	}
+	return nil
+}
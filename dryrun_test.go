@@ -0,0 +1,68 @@
+package jpush
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dryrun.jsonl")
+
+	c := NewJPushClient("key", "secret")
+	c.EnableCidPool(&CidPoolOptions{BatchSize: 10})
+	log, err := c.EnableDryRun(&DryRunOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, err := c.pushResult(context.Background(), coalescePayload(t, "a", "hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.MsgID <= dryRunMsgIDBase {
+		t.Fatalf("msg id = %d, want a fake msg id", ret.MsgID)
+	}
+
+	records := log.Records()
+	if len(records) != 2 || records[0].Family != API_PUSH || !strings.HasSuffix(records[0].URL, "/v3/push/cid?count=10&type=push") {
+		t.Fatalf("records = %+v, want the cid and push requests", records)
+	}
+	push := records[1]
+	if push.Method != http.MethodPost || push.URL != HOST_PUSH || !strings.Contains(push.Body, `"cid":"dryrun-`) {
+		t.Fatalf("push record = %+v", push)
+	}
+
+	// invalid pushes are rejected like JPush would
+	_, err = c.pushResult(context.Background(), []byte(`{"platform":"all","audience":"all"}`))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want a 400 *APIError", err)
+	}
+
+	details, err := c.GetReceivedDetail([]int64{1, 2})
+	if err != nil || len(details.Items) != 2 || details.Items[1].MsgID != 2 {
+		t.Fatalf("GetReceivedDetail = %+v, %v", details, err)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := 0
+	for s := bufio.NewScanner(f); s.Scan(); {
+		lines++
+	}
+	if lines != len(log.Records()) {
+		t.Fatalf("%d lines in the jsonl file, want %d", lines, len(log.Records()))
+	}
+}
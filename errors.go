@@ -0,0 +1,40 @@
+package jpush
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// APIError JPush 接口返回的错误信息
+type APIError struct {
+	StatusCode int    `json:"-"`       // HTTP 状态码
+	Code       int    `json:"code"`    // JPush 错误码
+	Message    string `json:"message"` // 错误描述
+	Body       string `json:"-"`       // 原始响应内容
+}
+
+func (e *APIError) Error() string {
+	if e.Code == 0 && e.Message == "" {
+		return fmt.Sprintf("jpush: http status %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("jpush: http status %d, code %d: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// parseAPIError builds an APIError from a non-2xx response body.
+// JPush answers with {"error": {"code": ..., "message": ...}}, some endpoints omit the wrapper.
+func parseAPIError(statusCode int, body []byte) *APIError {
+	e := &APIError{StatusCode: statusCode, Body: strings.TrimSpace(string(body))}
+
+	var wrapped struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil && wrapped.Error != nil {
+		e.Code = wrapped.Error.Code
+		e.Message = wrapped.Error.Message
+		return e
+	}
+
+	_ = json.Unmarshal(body, e)
+	return e
+}
@@ -0,0 +1,66 @@
+package jpush
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		code    int
+		message string
+		text    string
+	}{
+		{"wrapped", http.StatusBadRequest, `{"error":{"code":1003,"message":"msg_id is invalid"}}`, 1003, "msg_id is invalid", "jpush: http status 400, code 1003: msg_id is invalid"},
+		{"unwrapped", http.StatusUnauthorized, `{"code":1004,"message":"authentication failed"}`, 1004, "authentication failed", "jpush: http status 401, code 1004: authentication failed"},
+		{"not json", http.StatusBadGateway, "<html>bad gateway</html>\n", 0, "", "jpush: http status 502: <html>bad gateway</html>"},
+		{"empty", http.StatusServiceUnavailable, "", 0, "", "jpush: http status 503: "},
+		{"unrelated json", http.StatusNotFound, `{"msg":"not found"}`, 0, "", `jpush: http status 404: {"msg":"not found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := parseAPIError(tt.status, []byte(tt.body))
+			if e.StatusCode != tt.status || e.Code != tt.code || e.Message != tt.message || e.Body != strings.TrimSpace(tt.body) {
+				t.Fatalf("error = %+v", e)
+			}
+			if e.Error() != tt.text {
+				t.Fatalf("Error() = %q, want %q", e.Error(), tt.text)
+			}
+		})
+	}
+}
+
+func TestSendJSONStatusCodes(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusNoContent, http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError} {
+		c := NewJPushClient("key", "secret")
+		c.SetTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"cidlist":["c1"]}`
+			if status >= 300 {
+				body = `{"error":{"code":2002,"message":"rate limited"}}`
+			} else if status == http.StatusNoContent {
+				body = ""
+			}
+			return stubResponse(req, status, body), nil
+		}))
+
+		ret := &CidResponse{}
+		err := sendJSON(c.newRequest(http.MethodGet, HOST_CID), ret)
+		var apiErr *APIError
+		switch {
+		case status >= 300:
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != status || apiErr.Code != 2002 {
+				t.Fatalf("status %d: err = %v, want an *APIError", status, err)
+			}
+		case err != nil:
+			t.Fatalf("status %d: err = %v", status, err)
+		case status == http.StatusOK && len(ret.CidList) != 1:
+			t.Fatalf("status %d: response = %+v", status, ret)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Scorpio69t/jpush-api-golang-client"
+)
+
+// 查看或重放发件箱中的死信：
+//
+//	go run ./examples/outbox -dir /var/lib/app/outbox -list
+//	go run ./examples/outbox -dir /var/lib/app/outbox -replay
+//
+// -list 只读取发件箱，可以在服务运行时使用；-replay 需要打开发件箱，服务运行时会失败，
+// 此时应停止服务后重放，或在服务内调用 ReplayDeadLetters。
+func main() {
+	os.Exit(run())
+}
+
+// run does the work of main, so that the deferred Close releases the outbox lock before the exit
+func run() int {
+	dir := flag.String("dir", "outbox", "outbox directory")
+	list := flag.Bool("list", false, "list the pending pushes and the dead letters without sending anything")
+	replay := flag.Bool("replay", false, "move the dead letters back into the outbox and send them")
+	timeout := flag.Duration("timeout", time.Minute, "how long to wait for the replayed pushes")
+	flag.Parse()
+
+	if *list {
+		snapshot, err := jpush.ReadOutbox(*dir)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		fmt.Printf("%d pushes pending\n", len(snapshot.Pending))
+		for _, e := range snapshot.DeadLetters {
+			fmt.Printf("%s\tcid=%s\tattempts=%d\t%s\n", e.ID, e.Cid, e.Attempt, e.Error)
+		}
+	}
+
+	if !*replay {
+		return 0
+	}
+
+	c := jpush.NewJPushClientWithCredentials(jpush.EnvCredentials{})
+	outbox, err := c.OpenOutbox(*dir, nil)
+	if errors.Is(err, jpush.ErrOutboxLocked) {
+		fmt.Println("the outbox is opened by a running service, stop it before replaying")
+		return 1
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer outbox.Close()
+
+	n, err := outbox.ReplayDeadLetters()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	fmt.Printf("replaying %d pushes\n", n)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := outbox.Drain(ctx); err != nil {
+		fmt.Printf("%d pushes still pending: %v\n", outbox.Pending(), err)
+	}
+	return 0
+}
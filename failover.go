@@ -0,0 +1,210 @@
+package jpush
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FailoverOptions 多域名故障转移参数
+type FailoverOptions struct {
+	Hosts    map[APIFamily][]string // 各接口类别按优先级排列的等价域名，如 "https://api.jpush.cn"
+	CoolDown time.Duration          // 域名请求失败后被视为不可用的时长，默认 1 分钟
+}
+
+// EnableFailover 为客户端启用多域名故障转移。请求按顺序使用可用的域名，遇到连接错误或 5xx 响应时
+// 将该域名标记为不可用并换用下一个域名重试。POST 请求只有在携带 cid（幂等）时才会重试。
+// 需要在发送请求前调用。
+func (j *JPushClient) EnableFailover(opts *FailoverOptions) error {
+	f, err := newFailover(opts)
+	if err != nil {
+		return err
+	}
+	j.failover = f
+	return nil
+}
+
+// UnhealthyHosts 返回当前处于冷却期的域名及其恢复时间，未启用故障转移时返回 nil
+func (j *JPushClient) UnhealthyHosts() map[string]time.Time {
+	if j.failover == nil {
+		return nil
+	}
+	return j.failover.unhealthyHosts()
+}
+
+type failover struct {
+	hosts    map[APIFamily][]*url.URL
+	coolDown time.Duration
+
+	mu        sync.Mutex
+	unhealthy map[string]time.Time // host -> end of its cool-down
+}
+
+func newFailover(opts *FailoverOptions) (*failover, error) {
+	if opts == nil || len(opts.Hosts) == 0 {
+		return nil, errors.New("no failover hosts configured")
+	}
+
+	f := &failover{
+		hosts:     make(map[APIFamily][]*url.URL, len(opts.Hosts)),
+		coolDown:  opts.CoolDown,
+		unhealthy: make(map[string]time.Time),
+	}
+	if f.coolDown <= 0 {
+		f.coolDown = time.Minute
+	}
+
+	for family, hosts := range opts.Hosts {
+		for _, h := range hosts {
+			u, err := url.Parse(h)
+			if err != nil {
+				return nil, err
+			}
+			if u.Scheme == "" || u.Host == "" {
+				return nil, fmt.Errorf("invalid failover host %q of %s api", h, family)
+			}
+			f.hosts[family] = append(f.hosts[family], u)
+		}
+	}
+	return f, nil
+}
+
+// wrap sends each request to the hosts of its family, failing over when the request is idempotent
+func (f *failover) wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		hosts := f.hosts[apiFamilyOf(req.URL)]
+		if len(hosts) == 0 {
+			return next.RoundTrip(req)
+		}
+
+		order := f.order(hosts)
+		if isStreamed(req) {
+			// a streamed body can be read once, send it to the first host as it is
+			resp, err := next.RoundTrip(withHost(req, order[0], nil))
+			f.failed(order[0], resp, err)
+			return resp, err
+		}
+
+		var body []byte
+		if req.Body != nil {
+			var err error
+			body, err = io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !isIdempotent(req.Method, body) {
+			order = order[:1]
+		}
+
+		var resp *http.Response
+		var err error
+		for i, host := range order {
+			resp, err = next.RoundTrip(withHost(req, host, body))
+			if !f.failed(host, resp, err) || i == len(order)-1 || req.Context().Err() != nil {
+				break
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}
+		return resp, err
+	})
+}
+
+// failed records the outcome of a request to host and tells whether to try the next host
+func (f *failover) failed(host *url.URL, resp *http.Response, err error) bool {
+	bad := (err != nil && !errors.Is(err, context.Canceled)) || (err == nil && resp.StatusCode >= http.StatusInternalServerError)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if bad {
+		f.unhealthy[host.Host] = time.Now().Add(f.coolDown)
+	} else {
+		delete(f.unhealthy, host.Host)
+	}
+	return bad
+}
+
+// order returns the healthy hosts in configured order followed by those cooling down, soonest back first
+func (f *failover) order(hosts []*url.URL) []*url.URL {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	var healthy, cooling []*url.URL
+	for _, h := range hosts {
+		if until, ok := f.unhealthy[h.Host]; ok && now.Before(until) {
+			cooling = append(cooling, h)
+		} else {
+			healthy = append(healthy, h)
+		}
+	}
+	sort.SliceStable(cooling, func(i, k int) bool {
+		return f.unhealthy[cooling[i].Host].Before(f.unhealthy[cooling[k].Host])
+	})
+	return append(healthy, cooling...)
+}
+
+func (f *failover) unhealthyHosts() map[string]time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	ret := make(map[string]time.Time)
+	for host, until := range f.unhealthy {
+		if now.Before(until) {
+			ret[host] = until
+		}
+	}
+	return ret
+}
+
+// withHost returns a copy of req sent to host with a fresh body
+func withHost(req *http.Request, host *url.URL, body []byte) *http.Request {
+	r := req.Clone(req.Context())
+	u := *req.URL
+	u.Scheme = host.Scheme
+	u.Host = host.Host
+	r.URL = &u
+	r.Host = ""
+
+	if body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		r.ContentLength = int64(len(body))
+	}
+	return r
+}
+
+// isStreamed tells whether the body of req cannot be replayed nor buffered up front, like a multipart upload
+func isStreamed(req *http.Request) bool {
+	return req.Body != nil && req.GetBody == nil && req.ContentLength == -1
+}
+
+// isIdempotent tells whether a request may be sent again, POST bodies must carry a cid
+func isIdempotent(method string, body []byte) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		var v struct {
+			Cid string `json:"cid"`
+		}
+		return json.Unmarshal(body, &v) == nil && v.Cid != ""
+	}
+	return false
+}
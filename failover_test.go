@@ -0,0 +1,118 @@
+package jpush
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func failoverServer(status int, hits *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/v3/push" || !strings.Contains(string(body), "alert") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"sendno":"0","msg_id":"1"}`))
+	}))
+}
+
+func TestFailover(t *testing.T) {
+	var badHits, goodHits atomic.Int32
+	bad := failoverServer(http.StatusBadGateway, &badHits)
+	defer bad.Close()
+	good := failoverServer(http.StatusOK, &goodHits)
+	defer good.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	c := NewJPushClient("key", "secret")
+	err := c.EnableFailover(&FailoverOptions{Hosts: map[APIFamily][]string{
+		API_PUSH: {down.URL, bad.URL, good.URL},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	push := func(body string) error {
+		return sendJSON(c.newRequest(http.MethodPost, HOST_PUSH).SetBody([]byte(body)), nil)
+	}
+
+	if err := push(`{"cid":"c1","notification":{"alert":"hi"}}`); err != nil {
+		t.Fatalf("idempotent push: %v", err)
+	}
+	if badHits.Load() != 1 || goodHits.Load() != 1 {
+		t.Fatalf("hits bad=%d good=%d, want 1 and 1", badHits.Load(), goodHits.Load())
+	}
+	if n := len(c.UnhealthyHosts()); n != 2 {
+		t.Fatalf("%d unhealthy hosts, want 2", n)
+	}
+
+	// unhealthy hosts are skipped during their cool-down
+	if err := push(`{"notification":{"alert":"hi"}}`); err != nil {
+		t.Fatalf("push after failover: %v", err)
+	}
+	if badHits.Load() != 1 || goodHits.Load() != 2 {
+		t.Fatalf("hits bad=%d good=%d, want 1 and 2", badHits.Load(), goodHits.Load())
+	}
+}
+
+func TestFailoverRequiresCid(t *testing.T) {
+	var badHits, goodHits atomic.Int32
+	bad := failoverServer(http.StatusServiceUnavailable, &badHits)
+	defer bad.Close()
+	good := failoverServer(http.StatusOK, &goodHits)
+	defer good.Close()
+
+	c := NewJPushClient("key", "secret")
+	if err := c.EnableFailover(&FailoverOptions{Hosts: map[APIFamily][]string{API_PUSH: {bad.URL, good.URL}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := sendJSON(c.newRequest(http.MethodPost, HOST_PUSH).SetBody([]byte(`{"notification":{"alert":"hi"}}`)), nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want the 503 of the first host", err)
+	}
+	if goodHits.Load() != 0 {
+		t.Fatal("a push without cid must not be sent to another host")
+	}
+}
+
+func TestFailoverStreamedBody(t *testing.T) {
+	f, err := newFailover(&FailoverOptions{Hosts: map[APIFamily][]string{API_PUSH: {"https://a.example", "https://b.example"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []string
+	var sent string
+	rt := f.wrap(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		data, _ := io.ReadAll(req.Body)
+		sent = string(data)
+		return stubResponse(req, http.StatusBadGateway, ""), nil
+	}))
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = pw.Write([]byte(`{"cid":"c1"}`))
+		pw.Close()
+	}()
+	req, _ := http.NewRequest(http.MethodPut, HOST_PUSH, pr)
+	req.ContentLength = -1
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(hosts) != 1 || hosts[0] != "a.example" || sent != `{"cid":"c1"}` {
+		t.Fatalf("hosts = %v, sent = %q: a streamed body should be sent once to the first host", hosts, sent)
+	}
+}
@@ -0,0 +1,234 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	HOST_GEOFENCE = "https://api.jpush.cn/v3/geofences"
+
+	GEOFENCE_MIN_RADIUS = 100    // 围栏最小半径（米）
+	GEOFENCE_MAX_RADIUS = 100000 // 围栏最大半径（米）
+
+	formatClock = "15:04"
+)
+
+// GeoPoint 经纬度坐标
+type GeoPoint struct {
+	Latitude  float64 `json:"lat"` // 纬度，范围 [-90, 90]
+	Longitude float64 `json:"lon"` // 经度，范围 [-180, 180]
+}
+
+// GeofenceTimeWindow 每日生效时段，格式 HH:mm
+type GeofenceTimeWindow struct {
+	Start string `json:"start"` // 开始时间
+	End   string `json:"end"`   // 结束时间
+}
+
+type Geofence struct {
+	ResponseTiming
+	GeofenceID     string               `json:"geofence_id,omitempty"`     // 围栏 ID，创建后由服务端返回
+	Name           string               `json:"name"`                      // 围栏名称
+	Center         GeoPoint             `json:"center"`                    // 围栏中心点
+	Radius         int                  `json:"radius"`                    // 围栏半径（米）
+	Repeat         bool                 `json:"repeat"`                    // 是否重复触发
+	RepeatInterval int                  `json:"repeat_interval,omitempty"` // 重复触发的最小间隔（分钟），仅在 Repeat 为 true 时有效
+	TimeWindows    []GeofenceTimeWindow `json:"time_windows,omitempty"`    // 每日生效时段，不设置则全天生效
+	ValidStart     string               `json:"valid_start,omitempty"`     // 围栏生效开始时间（yyyy-MM-dd HH:mm:ss）
+	ValidEnd       string               `json:"valid_end,omitempty"`       // 围栏生效结束时间（yyyy-MM-dd HH:mm:ss）
+	Push           *PayLoad             `json:"push"`                      // 触发围栏时的推送内容
+}
+
+// NewGeofence 创建地理围栏
+func NewGeofence(name string, center GeoPoint, radius int, push *PayLoad) *Geofence {
+	return &Geofence{
+		Name:   name,
+		Center: center,
+		Radius: radius,
+		Push:   push,
+	}
+}
+
+// SetRepeat 设置重复触发及最小间隔（分钟）
+func (g *Geofence) SetRepeat(repeat bool, interval int) {
+	g.Repeat = repeat
+	g.RepeatInterval = interval
+}
+
+// AddTimeWindow 添加每日生效时段
+func (g *Geofence) AddTimeWindow(start, end string) {
+	g.TimeWindows = append(g.TimeWindows, GeofenceTimeWindow{Start: start, End: end})
+}
+
+// SetValidTime 设置围栏生效时间范围
+func (g *Geofence) SetValidTime(start, end time.Time) {
+	g.ValidStart = start.Format(formatTime)
+	g.ValidEnd = end.Format(formatTime)
+}
+
+// Validate 校验围栏定义
+func (g *Geofence) Validate() error {
+	if g.Name == "" {
+		return errors.New("geofence name is empty")
+	}
+	if g.Center.Latitude < -90 || g.Center.Latitude > 90 {
+		return fmt.Errorf("invalid latitude %v", g.Center.Latitude)
+	}
+	if g.Center.Longitude < -180 || g.Center.Longitude > 180 {
+		return fmt.Errorf("invalid longitude %v", g.Center.Longitude)
+	}
+	if g.Radius < GEOFENCE_MIN_RADIUS || g.Radius > GEOFENCE_MAX_RADIUS {
+		return fmt.Errorf("radius must be between %d and %d meters", GEOFENCE_MIN_RADIUS, GEOFENCE_MAX_RADIUS)
+	}
+	if g.RepeatInterval < 0 {
+		return errors.New("repeat interval must not be negative")
+	}
+
+	for _, w := range g.TimeWindows {
+		start, err := time.Parse(formatClock, w.Start)
+		if err != nil {
+			return fmt.Errorf("invalid time window start %q", w.Start)
+		}
+		end, err := time.Parse(formatClock, w.End)
+		if err != nil {
+			return fmt.Errorf("invalid time window end %q", w.End)
+		}
+		if !start.Before(end) {
+			return fmt.Errorf("time window %s-%s ends before it starts", w.Start, w.End)
+		}
+	}
+
+	if g.ValidStart != "" || g.ValidEnd != "" {
+		start, err := time.Parse(formatTime, g.ValidStart)
+		if err != nil {
+			return fmt.Errorf("invalid valid start %q", g.ValidStart)
+		}
+		end, err := time.Parse(formatTime, g.ValidEnd)
+		if err != nil {
+			return fmt.Errorf("invalid valid end %q", g.ValidEnd)
+		}
+		if !start.Before(end) {
+			return errors.New("valid end is before valid start")
+		}
+	}
+
+	if g.Push == nil || (g.Push.Notification == nil && g.Push.Message == nil) {
+		return errors.New("geofence push must contain a notification or message")
+	}
+	return nil
+}
+
+// CreateGeofence 创建地理围栏，返回带有 GeofenceID 的围栏
+func (j *JPushClient) CreateGeofence(g *Geofence) (*Geofence, error) {
+	if g == nil {
+		return nil, errors.New("geofence is nil")
+	}
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	body, err = j.withDefaults(body, "push")
+	if err != nil {
+		return nil, err
+	}
+
+	req := j.newRequest(http.MethodPost, HOST_GEOFENCE)
+	req.SetBody(body)
+
+	var resp struct {
+		GeofenceID string `json:"geofence_id"`
+	}
+	if err := sendJSON(req, &resp); err != nil {
+		return nil, err
+	}
+
+	ret := *g
+	ret.GeofenceID = resp.GeofenceID
+	ret.setTiming(req.Timing())
+	return &ret, nil
+}
+
+// UpdateGeofence 更新地理围栏
+func (j *JPushClient) UpdateGeofence(id string, g *Geofence) error {
+	if id == "" {
+		return errors.New("geofence id is empty")
+	}
+	if g == nil {
+		return errors.New("geofence is nil")
+	}
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	body, err = j.withDefaults(body, "push")
+	if err != nil {
+		return err
+	}
+
+	req := j.newRequest(http.MethodPut, HOST_GEOFENCE+"/"+url.PathEscape(id))
+	req.SetBody(body)
+
+	return sendJSON(req, nil)
+}
+
+// GetGeofence 获取地理围栏
+func (j *JPushClient) GetGeofence(id string) (*Geofence, error) {
+	if id == "" {
+		return nil, errors.New("geofence id is empty")
+	}
+
+	req := j.newRequest(http.MethodGet, HOST_GEOFENCE+"/"+url.PathEscape(id))
+
+	ret := &Geofence{}
+	if err := sendJSON(req, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// GeofenceList 地理围栏列表
+type GeofenceList struct {
+	ResponseTiming
+	Geofences []Geofence `json:"geofences"` // 围栏列表
+}
+
+// ListGeofences 获取地理围栏列表，page 从 1 开始
+func (j *JPushClient) ListGeofences(page int) (*GeofenceList, error) {
+	if page <= 0 {
+		page = 1
+	}
+
+	req := j.newRequest(http.MethodGet, HOST_GEOFENCE)
+	req.SetQueryParam("page", strconv.Itoa(page))
+
+	ret := &GeofenceList{}
+	if err := sendJSON(req, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// DeleteGeofence 删除地理围栏
+func (j *JPushClient) DeleteGeofence(id string) error {
+	if id == "" {
+		return errors.New("geofence id is empty")
+	}
+
+	req := j.newRequest(http.MethodDelete, HOST_GEOFENCE+"/"+url.PathEscape(id))
+
+	return sendJSON(req, nil)
+}
@@ -0,0 +1,116 @@
+package jpush
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGeofenceValidate(t *testing.T) {
+	push := NewPayLoad()
+	push.SetNotification(&Notification{Alert: "welcome"})
+
+	g := NewGeofence("store", GeoPoint{Latitude: 22.54, Longitude: 114.05}, 500, push)
+	g.AddTimeWindow("09:00", "21:00")
+	if err := g.Validate(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cases := map[string]func(g *Geofence){
+		"latitude":    func(g *Geofence) { g.Center.Latitude = 91 },
+		"longitude":   func(g *Geofence) { g.Center.Longitude = -181 },
+		"radius":      func(g *Geofence) { g.Radius = 10 },
+		"time window": func(g *Geofence) { g.AddTimeWindow("22:00", "08:00") },
+		"push":        func(g *Geofence) { g.Push = nil },
+	}
+	for name, mutate := range cases {
+		bad := *g
+		bad.TimeWindows = append([]GeofenceTimeWindow(nil), g.TimeWindows...)
+		mutate(&bad)
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestGeofenceMarshal(t *testing.T) {
+	var pf Platform
+	pf.AddAndroid()
+	var at Audience
+	at.SetTag([]string{"vip"})
+	push := NewPayLoad()
+	push.SetPlatform(&pf)
+	push.SetAudience(&at)
+	push.SetNotification(&Notification{Alert: "welcome"})
+
+	data, err := json.Marshal(NewGeofence("store", GeoPoint{Latitude: 1, Longitude: 2}, 500, push))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"platform":["android"],"audience":{"tag":["vip"]}`) {
+		t.Errorf("unexpected push json %s", data)
+	}
+
+	var g Geofence
+	if err := json.Unmarshal(data, &g); err != nil {
+		t.Fatal(err)
+	}
+	if g.Push.Platform.Interface().([]string)[0] != "android" {
+		t.Errorf("unexpected platform %v", g.Push.Platform.Interface())
+	}
+}
+
+func TestGeofencePushOptions(t *testing.T) {
+	var sent struct {
+		Push struct {
+			Options map[string]interface{} `json:"options"`
+		} `json:"push"`
+	}
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"geofence_id":"g1"}`, func(req *http.Request) {
+		data, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(data, &sent)
+	}))
+
+	push := NewPayLoad()
+	push.SetNotification(&Notification{Alert: "welcome"})
+	g := NewGeofence("store", GeoPoint{Latitude: 22.54, Longitude: 114.05}, 500, push)
+
+	// without client defaults the push keeps going to the sandbox
+	if _, err := c.CreateGeofence(g); err != nil {
+		t.Fatal(err)
+	}
+	if o := sent.Push.Options; o["apns_production"] != false {
+		t.Fatalf("sent options = %v, want the sandbox", o)
+	}
+
+	if err := c.SetEnvironment(ENVIRONMENT_PRODUCTION, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateGeofence("g1", g); err != nil {
+		t.Fatal(err)
+	}
+	if o := sent.Push.Options; o["apns_production"] != true {
+		t.Fatalf("sent options = %v, want the production default", o)
+	}
+}
+
+func TestGeofenceIDEscaped(t *testing.T) {
+	var paths []string
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"geofence_id":"a/b?c"}`, func(req *http.Request) {
+		paths = append(paths, req.Method+" "+req.URL.EscapedPath())
+	}))
+
+	if _, err := c.GetGeofence("a/b?c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteGeofence("a/b?c"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, ",") != "GET /v3/geofences/a%2Fb%3Fc,DELETE /v3/geofences/a%2Fb%3Fc" {
+		t.Fatalf("paths = %v", paths)
+	}
+}
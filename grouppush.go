@@ -0,0 +1,118 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	HOST_GROUP_PUSH          = "https://api.jpush.cn/v3/grouppush"
+	HOST_GROUP_PUSH_VALIDATE = "https://api.jpush.cn/v3/grouppush/validate"
+)
+
+// GroupPushClient 应用分组推送客户端，使用分组的 group key / group master secret 认证
+type GroupPushClient struct {
+	GroupKey          string // group key
+	GroupMasterSecret string // group master secret
+
+	transport   http.RoundTripper // 请求使用的 Transport，为空时使用包内共用的连接池
+	middlewares []Middleware      // 每个请求完成后调用的中间件
+}
+
+// GroupAppResult 分组中单个应用的推送结果
+type GroupAppResult struct {
+	PushResult
+	Error *APIError `json:"error,omitempty"` // 推送失败时的错误信息
+}
+
+// GroupPushResult 分组推送结果
+type GroupPushResult struct {
+	ResponseTiming
+	GroupMsgID string                     `json:"group_msgid"` // 分组推送消息 ID
+	Apps       map[string]*GroupAppResult `json:"-"`           // appKey 与推送结果的对应关系
+}
+
+func (g *GroupPushResult) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	g.Apps = make(map[string]*GroupAppResult)
+	for k, v := range raw {
+		if k == "group_msgid" {
+			if err := json.Unmarshal(v, &g.GroupMsgID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ret := &GroupAppResult{}
+		if err := json.Unmarshal(v, ret); err != nil {
+			return err
+		}
+		g.Apps[k] = ret
+	}
+	return nil
+}
+
+// NewGroupPushClient returns a new GroupPushClient
+func NewGroupPushClient(groupKey string, groupMasterSecret string) *GroupPushClient {
+	return &GroupPushClient{GroupKey: groupKey, GroupMasterSecret: groupMasterSecret}
+}
+
+// SetTransport 设置客户端所有请求使用的 Transport，未设置时使用包内共用的连接池
+func (g *GroupPushClient) SetTransport(transport http.RoundTripper) {
+	g.transport = transport
+}
+
+// Use 为客户端注册中间件，需要在发送请求前调用
+func (g *GroupPushClient) Use(middlewares ...Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// newRequest returns a request carrying the common JPush headers and the group basic auth
+func (g *GroupPushClient) newRequest(method, url string) *HttpRequest {
+	req := newAuthRequest(method, url, "group-"+g.GroupKey, g.GroupMasterSecret)
+	req.middlewares = g.middlewares
+	if g.transport != nil {
+		req.SetTransport(g.transport)
+	}
+	return req
+}
+
+// Push 向分组内所有应用推送
+func (g *GroupPushClient) Push(payload *PayLoad) (*GroupPushResult, error) {
+	return g.sendGroupPush(HOST_GROUP_PUSH, payload)
+}
+
+// Validate 校验分组推送内容，不会实际推送
+func (g *GroupPushClient) Validate(payload *PayLoad) (*GroupPushResult, error) {
+	return g.sendGroupPush(HOST_GROUP_PUSH_VALIDATE, payload)
+}
+
+// sendGroupPush sends a group push request and returns the typed result
+func (g *GroupPushClient) sendGroupPush(url string, payload *PayLoad) (*GroupPushResult, error) {
+	if payload == nil {
+		return nil, errors.New("payload is nil")
+	}
+
+	body, err := payload.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	body, err = withSandboxDefault(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := g.newRequest(http.MethodPost, url)
+	req.SetBody(body)
+
+	ret := &GroupPushResult{}
+	if err := sendJSON(req, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
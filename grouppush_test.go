@@ -0,0 +1,65 @@
+package jpush
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestGroupPushResultUnmarshal(t *testing.T) {
+	data := `{"group_msgid":"g1","app1":{"sendno":"0","msg_id":"123"},"app2":{"error":{"code":1011,"message":"cannot find user by this audience"}}}`
+
+	var ret GroupPushResult
+	if err := json.Unmarshal([]byte(data), &ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret.GroupMsgID != "g1" || len(ret.Apps) != 2 {
+		t.Fatalf("unexpected result %+v", ret)
+	}
+	if ret.Apps["app1"].MsgID != 123 || ret.Apps["app1"].Error != nil {
+		t.Errorf("unexpected app1 result %+v", ret.Apps["app1"])
+	}
+	if e := ret.Apps["app2"].Error; e == nil || e.Code != 1011 {
+		t.Errorf("unexpected app2 result %+v", ret.Apps["app2"])
+	}
+}
+
+func TestGroupPushRequest(t *testing.T) {
+	var path, user string
+	var sent map[string]interface{}
+	g := NewGroupPushClient("gk", "gs")
+	g.SetTransport(stubTransport(http.StatusOK, `{"group_msgid":"g1","app1":{"sendno":"0","msg_id":"123"}}`, func(req *http.Request) {
+		path = req.URL.Path
+		user, _, _ = req.BasicAuth()
+		data, _ := io.ReadAll(req.Body)
+		_ = json.Unmarshal(data, &sent)
+	}))
+	var infos []RequestInfo
+	g.Use(func(info *RequestInfo) { infos = append(infos, *info) })
+
+	payload := NewPayLoad()
+	payload.SetPlatform(&Platform{})
+	payload.SetAudience(&Audience{})
+	payload.SetNotification(&Notification{Alert: "hello"})
+
+	ret, err := g.Push(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.GroupMsgID != "g1" || ret.Apps["app1"].MsgID != 123 {
+		t.Errorf("unexpected result %+v", ret)
+	}
+	if path != "/v3/grouppush" || user != "group-gk" {
+		t.Errorf("unexpected request %s as %s", path, user)
+	}
+	if sent["notification"] == nil {
+		t.Errorf("payload not sent: %v", sent)
+	}
+	if o, _ := sent["options"].(map[string]interface{}); o["apns_production"] != false {
+		t.Errorf("sent options = %v, want the sandbox", sent["options"])
+	}
+	if len(infos) != 1 || infos[0].StatusCode != http.StatusOK {
+		t.Errorf("middleware not called once: %+v", infos)
+	}
+}
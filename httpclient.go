@@ -2,6 +2,7 @@ package jpush
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -25,7 +26,7 @@ func SendPostString(url string, content, appKey, masterSecret string) (string, e
 	req.SetBasicAuth(appKey, masterSecret)
 	req.SetHeader("Content-Type", CONTENT_TYPE_JSON)
 	req.SetProtocolVersion("HTTP/1.1")
-	req.SetBody(content)
+	req.SetBody(string(legacyPushBody([]byte(content))))
 
 	return req.String()
 }
@@ -39,7 +40,7 @@ func SendPostBytes(url string, content []byte, appKey, masterSecret string) (str
 	req.SetBasicAuth(appKey, masterSecret)
 	req.SetHeader("Content-Type", CONTENT_TYPE_JSON)
 	req.SetProtocolVersion("HTTP/1.1")
-	req.SetBody(content)
+	req.SetBody(legacyPushBody(content))
 
 	return req.String()
 }
@@ -47,7 +48,7 @@ func SendPostBytes(url string, content []byte, appKey, masterSecret string) (str
 // SendPostBytes2 sends a post request and returns the response body as bytes
 func SendPostBytes2(url string, data []byte, appKey, masterSecret string) (string, error) {
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(legacyPushBody(data)))
 	if err != nil {
 		return "", err
 	}
@@ -75,6 +76,15 @@ func SendPostBytes2(url string, data []byte, appKey, masterSecret string) (strin
 	return string(body), nil
 }
 
+// legacyPushBody keeps the sandbox default in the pushes sent by the helpers above,
+// bodies that are not json objects are sent as they are
+func legacyPushBody(data []byte) []byte {
+	if body, err := withSandboxDefault(data); err == nil {
+		return body
+	}
+	return data
+}
+
 // SendGet sends a get request and returns the response body as string
 func SendGet(url, appKey, masterSecret string) (string, error) {
 	req := Get(url)
@@ -87,3 +97,86 @@ func SendGet(url, appKey, masterSecret string) (string, error) {
 
 	return req.String()
 }
+
+// apiResponse is the raw outcome of a JPush API call
+type apiResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Timing     HTTPTiming
+}
+
+// decode decodes a successful JSON response body into v, non-2xx responses are returned as *APIError
+func (r *apiResponse) decode(v interface{}) error {
+	if r.StatusCode < 200 || r.StatusCode >= 300 {
+		return parseAPIError(r.StatusCode, r.Body)
+	}
+
+	if v == nil || len(bytes.TrimSpace(r.Body)) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return err
+	}
+	if t, ok := v.(timed); ok {
+		t.setTiming(r.Timing)
+	}
+	return nil
+}
+
+// execute executes the request and reads the whole response
+func execute(req *HttpRequest) (*apiResponse, error) {
+	resp, err := req.getResponse()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body == nil {
+		err = errors.New("response body is nil")
+		req.finish(resp.StatusCode, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	req.finish(resp.StatusCode, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: body, Timing: req.Timing()}, nil
+}
+
+// sendJSON executes the request and decodes a successful JSON response body into v.
+// Non-2xx responses are returned as *APIError.
+func sendJSON(req *HttpRequest, v interface{}) error {
+	resp, err := execute(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.decode(v)
+}
+
+// newAuthRequest returns a request carrying the common JPush headers and basic auth
+func newAuthRequest(method, url, username, password string) *HttpRequest {
+	var req *HttpRequest
+	switch method {
+	case http.MethodPost:
+		req = Post(url)
+	case http.MethodPut:
+		req = Put(url)
+	case http.MethodDelete:
+		req = Delete(url)
+	default:
+		req = Get(url)
+	}
+	req.SetTimeout(DEFAULT_CONNECT_TIMEOUT*time.Second, DEFAULT_READ_WRITE_TIMEOUT*time.Second)
+	req.SetHeader("Connection", "Keep-Alive")
+	req.SetHeader("Charset", CHARSET)
+	req.SetBasicAuth(username, password)
+	req.SetHeader("Content-Type", CONTENT_TYPE_JSON)
+	req.SetProtocolVersion("HTTP/1.1")
+
+	return req
+}
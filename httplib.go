@@ -9,8 +9,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
 	"os"
 	"strconv"
@@ -27,6 +30,20 @@ type HttpRequest struct {
 	tlsConfig        *tls.Config
 	proxy            func(*http.Request) (*url.URL, error)
 	transport        http.RoundTripper
+	parts            []multipartPart
+	wrappers         []func(http.RoundTripper) http.RoundTripper
+	timing           *requestTiming
+	middlewares      []Middleware
+	err              error
+}
+
+// multipartPart is a field or file of a multipart/form-data body.
+type multipartPart struct {
+	field       string
+	value       string
+	filename    string
+	contentType string
+	reader      io.Reader
 }
 
 // Get returns *HttpRequest with GET method.
@@ -35,7 +52,7 @@ func Get(url string) *HttpRequest {
 	req.Method = "GET"
 	req.Header = make(http.Header)
 
-	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil, nil, nil, nil, nil, nil}
 }
 
 // Post returns *HttpRequest with POST method.
@@ -44,7 +61,7 @@ func Post(url string) *HttpRequest {
 	req.Method = "POST"
 	req.Header = make(http.Header)
 
-	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil, nil, nil, nil, nil, nil}
 }
 
 // Delete returns *HttpRequest with DELETE method.
@@ -53,7 +70,7 @@ func Delete(url string) *HttpRequest {
 	req.Method = "DELETE"
 	req.Header = make(http.Header)
 
-	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil, nil, nil, nil, nil, nil}
 }
 
 // Put returns *HttpRequest with PUT method.
@@ -62,17 +79,19 @@ func Put(url string) *HttpRequest {
 	req.Method = "PUT"
 	req.Header = make(http.Header)
 
-	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return &HttpRequest{url, &req, map[string]string{}, 60 * time.Second, 60 * time.Second, nil, nil, nil, nil, nil, nil, nil, nil}
 }
 
 // SetQueryParam replaces the request query values.
 func (h *HttpRequest) SetQueryParam(key, value string) *HttpRequest {
-	if h.req.URL == nil {
+	u, err := url.Parse(h.url)
+	if err != nil {
 		return h
 	}
-	q := h.req.URL.Query()
+	q := u.Query()
 	q.Add(key, value)
-	h.req.URL.RawQuery = q.Encode()
+	u.RawQuery = q.Encode()
+	h.url = u.String()
 	return h
 }
 
@@ -83,6 +102,14 @@ func (h *HttpRequest) SetTimeout(connectTimeout, readWriteTimeout time.Duration)
 	return h
 }
 
+// SetContext sets the context used to cancel the request.
+func (h *HttpRequest) SetContext(ctx context.Context) *HttpRequest {
+	if ctx != nil {
+		h.req = h.req.WithContext(ctx)
+	}
+	return h
+}
+
 // SetTLSConfig sets tls connection configurations if visiting https url.
 func (h *HttpRequest) SetTLSConfig(config *tls.Config) *HttpRequest {
 	h.tlsConfig = config
@@ -135,6 +162,12 @@ func (h *HttpRequest) SetTransport(transport http.RoundTripper) *HttpRequest {
 	return h
 }
 
+// wrapTransport adds a wrapper around the transport, wrappers added later see the request first.
+func (h *HttpRequest) wrapTransport(wrap func(http.RoundTripper) http.RoundTripper) *HttpRequest {
+	h.wrappers = append(h.wrappers, wrap)
+	return h
+}
+
 // SetProxy sets proxy for HttpClient.
 // example:
 //
@@ -207,6 +240,69 @@ func (h *HttpRequest) SetBody(body interface{}) *HttpRequest {
 	return h
 }
 
+// SetMultipartField adds a form field to a multipart/form-data body.
+// Multipart parts cannot be combined with SetBody.
+func (h *HttpRequest) SetMultipartField(field, value string) *HttpRequest {
+	h.parts = append(h.parts, multipartPart{field: field, value: value})
+	return h
+}
+
+// SetMultipartFile adds a file part to a multipart/form-data body.
+// The reader is streamed when the request is sent, it is never buffered as a whole.
+// contentType defaults to application/octet-stream.
+func (h *HttpRequest) SetMultipartFile(field, filename, contentType string, r io.Reader) *HttpRequest {
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	h.parts = append(h.parts, multipartPart{field: field, filename: filename, contentType: contentType, reader: r})
+	return h
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// setMultipartBody streams the multipart parts and params through a pipe as request body.
+// The pipe is closed by the transport once the request has been written or has failed.
+func (h *HttpRequest) setMultipartBody() {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+
+	parts := h.parts
+	for k, v := range h.params {
+		parts = append(parts, multipartPart{field: k, value: v})
+	}
+
+	go func() {
+		for _, p := range parts {
+			if p.reader == nil {
+				if err := w.WriteField(p.field, p.value); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				continue
+			}
+
+			header := make(textproto.MIMEHeader)
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+				quoteEscaper.Replace(p.field), quoteEscaper.Replace(p.filename)))
+			header.Set("Content-Type", p.contentType)
+			part, err := w.CreatePart(header)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(part, p.reader); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(w.Close())
+	}()
+
+	h.req.Header.Set("Content-Type", w.FormDataContentType())
+	h.req.Body = pr
+	h.req.ContentLength = -1
+}
+
 // GetHeader returns header data.
 func (h *HttpRequest) GetHeader() http.Header {
 	return h.req.Header
@@ -229,8 +325,15 @@ func (h *HttpRequest) GetParam(key string) string {
 
 // getResponse executes the request and returns the response.
 func (h *HttpRequest) getResponse() (*http.Response, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	if len(h.parts) > 0 && h.req.Body != nil {
+		return nil, errors.New("multipart parts cannot be combined with SetBody")
+	}
+
 	var paramBody string
-	if len(h.params) > 0 {
+	if len(h.params) > 0 && len(h.parts) == 0 {
 		v := url.Values{}
 		for k, val := range h.params {
 			v.Set(k, val)
@@ -238,10 +341,10 @@ func (h *HttpRequest) getResponse() (*http.Response, error) {
 		paramBody = v.Encode()
 	}
 
-	if h.req.Body != nil {
+	if h.req.Body != nil && len(paramBody) > 0 {
 		if strings.Contains(h.req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
 			b, _ := io.ReadAll(h.req.Body)
-			paramBody = string(b) + "&" + paramBody
+			h.SetBody(string(b) + "&" + paramBody)
 		} else {
 			return nil, errors.New("please use SetBody method instead")
 		}
@@ -275,39 +378,138 @@ func (h *HttpRequest) getResponse() (*http.Response, error) {
 	trans := h.transport
 
 	if trans == nil {
-		trans = &http.Transport{
-			TLSClientConfig: h.tlsConfig,
-			Proxy:           h.proxy,
-			DialContext:     TimeoutDialer(h.connectTimeout, h.readWriteTimeout),
+		trans = defaultTransport
+		if h.tlsConfig != nil || h.proxy != nil {
+			trans = h.dedicatedTransport(nil)
 		}
-	} else {
-		if t, ok := trans.(*http.Transport); ok {
-			if t.TLSClientConfig == nil {
-				t.TLSClientConfig = h.tlsConfig
-			}
+	} else if t, ok := trans.(*http.Transport); ok && (h.tlsConfig != nil || h.proxy != nil) {
+		// a transport set by the caller may be shared and in use, it is neither read nor modified
+		// unless the request asks for its own tls config or proxy
+		trans = h.dedicatedTransport(t)
+	}
 
-			if t.Proxy == nil {
-				t.Proxy = h.proxy
-			}
+	for _, wrap := range h.wrappers {
+		trans = wrap(trans)
+	}
 
-			if t.DialContext == nil {
-				t.DialContext = TimeoutDialer(h.connectTimeout, h.readWriteTimeout)
-			}
-		}
+	if len(h.parts) > 0 {
+		h.setMultipartBody()
 	}
 
 	client := &http.Client{
 		Transport: trans,
 	}
 
+	if h.timing == nil {
+		h.timing = &requestTiming{}
+	}
+	ctx, cancel := h.req.Context(), context.CancelFunc(func() {})
+	if h.readWriteTimeout > 0 {
+		// a deadline on the request rather than on the connection, so that pooled connections stay usable
+		ctx, cancel = context.WithTimeout(ctx, h.connectTimeout+h.readWriteTimeout)
+	}
+	h.req = h.req.WithContext(httptrace.WithClientTrace(ctx, h.timing.trace()))
+
 	resp, err := client.Do(h.req)
 	if err != nil {
+		cancel()
+		h.finish(0, err)
 		return nil, err
 	}
+	if resp.Body == nil {
+		cancel()
+	} else {
+		resp.Body = &cancelBody{resp.Body, cancel}
+	}
 
 	return resp, nil
 }
 
+// dedicatedTransport returns a transport applying the tls config and proxy of the request,
+// based on t when it is not nil. It does not keep idle connections since it is used once.
+func (h *HttpRequest) dedicatedTransport(t *http.Transport) *http.Transport {
+	if t == nil {
+		t = newPooledTransport(0)
+		t.TLSClientConfig = h.tlsConfig
+		t.Proxy = h.proxy
+	} else {
+		t = t.Clone()
+		if h.tlsConfig != nil {
+			t.TLSClientConfig = h.tlsConfig
+		}
+		if h.proxy != nil {
+			t.Proxy = h.proxy
+		}
+	}
+	t.DisableKeepAlives = true
+	return t
+}
+
+// cancelBody releases the request context once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+const DEFAULT_MAX_IDLE_CONNS_PER_HOST = 16 // 默认每个域名保持的空闲连接数
+
+// defaultTransport is shared by the requests without their own transport so that connections are pooled
+var defaultTransport = newPooledTransport(DEFAULT_MAX_IDLE_CONNS_PER_HOST)
+
+// newPooledTransport returns a transport keeping up to maxIdlePerHost idle connections per host.
+// Request timeouts are enforced through the request context, not through connection deadlines.
+func newPooledTransport(maxIdlePerHost int) *http.Transport {
+	return &http.Transport{
+		TLSClientConfig: &tls.Config{},
+		Proxy:           http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   DEFAULT_CONNECT_TIMEOUT * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: DEFAULT_READ_WRITE_TIMEOUT * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
+
+// Timing returns the timing breakdown of the request, Total is set once the response body has been read.
+func (h *HttpRequest) Timing() HTTPTiming {
+	if h.timing == nil {
+		return HTTPTiming{}
+	}
+	return h.timing.get()
+}
+
+// finish records the end of the request and passes it to the middlewares
+func (h *HttpRequest) finish(statusCode int, err error) {
+	if h.timing == nil || !h.timing.finish() || len(h.middlewares) == 0 {
+		return
+	}
+
+	info := &RequestInfo{
+		Method:     h.req.Method,
+		URL:        h.url,
+		StatusCode: statusCode,
+		Err:        err,
+		Timing:     h.timing.get(),
+	}
+	if h.req.URL != nil {
+		info.Family = apiFamilyOf(h.req.URL)
+	}
+	for _, mw := range h.middlewares {
+		mw(info)
+	}
+}
+
 // TimeoutDialer returns functions of connection dialer with timeout settings for http.Transport Dial field.
 func TimeoutDialer(connectTimeout time.Duration, readWriteTimeout time.Duration) func(ctx context.Context, network, addr string) (c net.Conn, err error) {
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
@@ -332,8 +534,40 @@ func TimeoutDialer(connectTimeout time.Duration, readWriteTimeout time.Duration)
 }
 
 // Response executes request client gets response in the return.
+// The request is finished for timing and middlewares once the response body is closed.
 func (h *HttpRequest) Response() (*http.Response, error) {
-	return h.getResponse()
+	resp, err := h.getResponse()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body == nil {
+		h.finish(resp.StatusCode, nil)
+	} else {
+		resp.Body = &finishBody{ReadCloser: resp.Body, h: h, statusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// finishBody finishes its request when closed, with the first read error other than io.EOF
+type finishBody struct {
+	io.ReadCloser
+	h          *HttpRequest
+	statusCode int
+	err        error
+}
+
+func (b *finishBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *finishBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.h.finish(b.statusCode, b.err)
+	return err
 }
 
 // Bytes executes request client gets response body in bytes.
@@ -343,10 +577,14 @@ func (h *HttpRequest) Bytes() ([]byte, error) {
 		return nil, err
 	}
 	if resp.Body == nil {
+		h.finish(resp.StatusCode, nil)
 		return nil, nil
 	}
 	defer resp.Body.Close()
-	return io.ReadAll(resp.Body)
+
+	data, err := io.ReadAll(resp.Body)
+	h.finish(resp.StatusCode, err)
+	return data, err
 }
 
 // String returns the body string in response.
@@ -359,25 +597,38 @@ func (h *HttpRequest) String() (string, error) {
 	return string(data), nil
 }
 
-// Status returns the response status.
+// Status returns the response status, 0 when the request fails.
 func (h *HttpRequest) Status() int {
-	resp, _ := h.Response()
+	resp, err := h.Response()
+	if err != nil {
+		return 0
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
 	return resp.StatusCode
 }
 
 // ToFile saves the body data in response to one file.
 func (h *HttpRequest) ToFile(file string) error {
-	resp, err := h.Response()
+	resp, err := h.getResponse()
 	if err != nil {
 		return err
 	}
+	if resp.Body == nil {
+		h.finish(resp.StatusCode, nil)
+		return nil
+	}
 	defer resp.Body.Close()
+
 	f, err := os.Create(file)
-	if err != nil {
-		return err
+	if err == nil {
+		_, err = io.Copy(f, resp.Body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
 	}
-	defer f.Close()
-	_, err = io.Copy(f, resp.Body)
+	h.finish(resp.StatusCode, err)
 	return err
 }
 
@@ -0,0 +1,157 @@
+package jpush
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHttpRequestMultipart(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != -1 {
+			t.Errorf("expected streamed body, got content length %d", r.ContentLength)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+			return
+		}
+		if got := r.FormValue("image_type"); got != "1" {
+			t.Errorf("image_type = %q, want 1", got)
+		}
+		f, h, err := r.FormFile("jiguang_file")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+			return
+		}
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		if h.Filename != "icon.png" || h.Header.Get("Content-Type") != "image/png" || string(data) != "png-bytes" {
+			t.Errorf("unexpected file part %q %q %q", h.Filename, h.Header.Get("Content-Type"), data)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	req := Post(ts.URL)
+	req.SetMultipartField("image_type", "1")
+	req.SetMultipartFile("jiguang_file", "icon.png", "image/png", strings.NewReader("png-bytes"))
+	ret, err := req.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret != "ok" {
+		t.Errorf("response = %q", ret)
+	}
+}
+
+func TestHttpRequestMultipartWithBody(t *testing.T) {
+	req := Post("http://127.0.0.1")
+	req.SetBody("{}")
+	req.SetMultipartField("k", "v")
+	if _, err := req.Response(); err == nil {
+		t.Error("expected error when mixing SetBody and multipart parts")
+	}
+}
+
+func TestRequestsReuseConnections(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"sendno":"0","msg_id":"1"}`))
+	}))
+	defer ts.Close()
+
+	c := NewJPushClient("key", "secret")
+	var timings []HTTPTiming
+	for i := 0; i < 2; i++ {
+		req := c.newRequest(http.MethodPost, ts.URL+"/v3/push").SetBody([]byte(`{}`))
+		resp, err := execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		timings = append(timings, resp.Timing)
+	}
+	if timings[0].Reused || !timings[1].Reused {
+		t.Fatalf("timings = %+v, want the second request on a pooled connection", timings)
+	}
+}
+
+func TestHttpRequestBodyAndParams(t *testing.T) {
+	var body, contentType string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, contentType = string(data), r.Header.Get("Content-Type")
+	}))
+	defer ts.Close()
+
+	// a json body without params is sent as is
+	if _, err := Post(ts.URL).SetHeader("Content-Type", CONTENT_TYPE_JSON).SetBody(`{"a":1}`).Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"a":1}` || contentType != CONTENT_TYPE_JSON {
+		t.Fatalf("body = %q, content type = %q", body, contentType)
+	}
+
+	// params are appended to a form body
+	if _, err := Post(ts.URL).SetHeader("Content-Type", CONTENT_TYPE_FORM).SetBody("a=1").SetParam("b", "2").Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	if body != "a=1&b=2" {
+		t.Fatalf("body = %q, want a=1&b=2", body)
+	}
+
+	// params alone become a form body
+	if _, err := Post(ts.URL).SetParam("b", "2").Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	if body != "b=2" || contentType != CONTENT_TYPE_FORM {
+		t.Fatalf("body = %q, content type = %q", body, contentType)
+	}
+
+	if _, err := Post(ts.URL).SetHeader("Content-Type", CONTENT_TYPE_JSON).SetBody(`{"a":1}`).SetParam("b", "2").Bytes(); err == nil {
+		t.Fatal("params cannot be combined with a json body")
+	}
+}
+
+func TestHttpRequestFinishedByEveryReader(t *testing.T) {
+	var infos []RequestInfo
+	newReq := func() *HttpRequest {
+		req := Get(HOST_PUSH)
+		req.SetTransport(stubTransport(http.StatusOK, "report", nil))
+		req.middlewares = []Middleware{func(info *RequestInfo) { infos = append(infos, *info) }}
+		return req
+	}
+
+	file := filepath.Join(t.TempDir(), "report.txt")
+	if err := newReq().ToFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "report" {
+		t.Fatalf("file = %q", data)
+	}
+
+	resp, err := newReq().Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("%d requests finished before the response body was closed, want 1", len(infos))
+	}
+	_, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body.Close()
+
+	if status := newReq().Status(); status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+	if len(infos) != 3 {
+		t.Fatalf("%d requests finished, want 3", len(infos))
+	}
+	for _, info := range infos {
+		if info.StatusCode != http.StatusOK || info.Err != nil || info.Family != API_PUSH {
+			t.Fatalf("info = %+v", info)
+		}
+	}
+}
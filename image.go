@@ -0,0 +1,180 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type ImageType int
+
+const (
+	IMAGE_LARGE_ICON  ImageType = 1 // 大图标
+	IMAGE_BIG_PICTURE ImageType = 2 // 大图片
+)
+
+const mediaIDPrefix = "jgmedia-"
+
+// MediaID 图片 API 上传后返回的 media_id，可用于通知的大图标、大图片等字段
+type MediaID string
+
+func (m MediaID) String() string {
+	return string(m)
+}
+
+// Valid 判断是否为图片 API 返回的 media_id
+func (m MediaID) Valid() bool {
+	return strings.HasPrefix(string(m), mediaIDPrefix) && len(m) > len(mediaIDPrefix)
+}
+
+type ImageUrlRequest struct {
+	ImageType       ImageType `json:"image_type"`                  // 图片类型，1：大图标，2：大图片
+	ImageUrl        string    `json:"image_url,omitempty"`         // 图片地址，未单独指定厂商地址时使用
+	JiguangImageUrl string    `json:"jiguang_image_url,omitempty"` // 极光通道图片地址
+	XiaomiImageUrl  string    `json:"xiaomi_image_url,omitempty"`  // 小米通道图片地址
+	HuaweiImageUrl  string    `json:"huawei_image_url,omitempty"`  // 华为通道图片地址
+	OppoImageUrl    string    `json:"oppo_image_url,omitempty"`    // OPPO 通道图片地址
+	FcmImageUrl     string    `json:"fcm_image_url,omitempty"`     // FCM 通道图片地址
+}
+
+// ImageFile 待上传的图片文件
+type ImageFile struct {
+	Name        string    // 文件名
+	ContentType string    // 文件类型，默认为 application/octet-stream
+	Reader      io.Reader // 文件内容，发送时以流的方式读取
+}
+
+type ImageFileRequest struct {
+	ImageType   ImageType  // 图片类型，1：大图标，2：大图片
+	JiguangFile *ImageFile // 极光通道图片文件
+	XiaomiFile  *ImageFile // 小米通道图片文件
+	HuaweiFile  *ImageFile // 华为通道图片文件
+	OppoFile    *ImageFile // OPPO 通道图片文件
+	FcmFile     *ImageFile // FCM 通道图片文件
+}
+
+type ImageResponse struct {
+	ResponseTiming
+	MediaID         MediaID `json:"media_id"`                    // 图片 media_id
+	JiguangImageUrl string  `json:"jiguang_image_url,omitempty"` // 极光通道图片地址
+	XiaomiImageUrl  string  `json:"xiaomi_image_url,omitempty"`  // 小米通道图片地址
+	HuaweiImageUrl  string  `json:"huawei_image_url,omitempty"`  // 华为通道图片地址
+	OppoImageUrl    string  `json:"oppo_image_url,omitempty"`    // OPPO 通道图片地址
+	FcmImageUrl     string  `json:"fcm_image_url,omitempty"`     // FCM 通道图片地址
+}
+
+// validate 校验图片地址请求
+func (r *ImageUrlRequest) validate() error {
+	if r.ImageType != IMAGE_LARGE_ICON && r.ImageType != IMAGE_BIG_PICTURE {
+		return errors.New("invalid image type")
+	}
+	if r.ImageUrl == "" && r.JiguangImageUrl == "" && r.XiaomiImageUrl == "" &&
+		r.HuaweiImageUrl == "" && r.OppoImageUrl == "" && r.FcmImageUrl == "" {
+		return errors.New("image url is empty")
+	}
+	return nil
+}
+
+// files 返回表单字段名与图片文件的对应关系
+func (r *ImageFileRequest) files() map[string]*ImageFile {
+	files := make(map[string]*ImageFile)
+	for field, f := range map[string]*ImageFile{
+		"jiguang_file": r.JiguangFile,
+		"xiaomi_file":  r.XiaomiFile,
+		"huawei_file":  r.HuaweiFile,
+		"oppo_file":    r.OppoFile,
+		"fcm_file":     r.FcmFile,
+	} {
+		if f != nil && f.Reader != nil {
+			files[field] = f
+		}
+	}
+	return files
+}
+
+// validate 校验图片文件请求
+func (r *ImageFileRequest) validate() error {
+	if r.ImageType != IMAGE_LARGE_ICON && r.ImageType != IMAGE_BIG_PICTURE {
+		return errors.New("invalid image type")
+	}
+	if len(r.files()) == 0 {
+		return errors.New("image file is empty")
+	}
+	return nil
+}
+
+// UploadImageByUrl 通过图片地址上传图片
+func (j *JPushClient) UploadImageByUrl(r *ImageUrlRequest) (*ImageResponse, error) {
+	return j.sendImageUrlRequest(http.MethodPost, HOST_IMAGES+"/byurls", r)
+}
+
+// UpdateImageByUrl 通过图片地址更新已上传的图片
+func (j *JPushClient) UpdateImageByUrl(mediaID MediaID, r *ImageUrlRequest) (*ImageResponse, error) {
+	if !mediaID.Valid() {
+		return nil, errors.New("invalid media id")
+	}
+	return j.sendImageUrlRequest(http.MethodPut, HOST_IMAGES+"/byurls/"+url.PathEscape(mediaID.String()), r)
+}
+
+// UploadImageByFile 通过图片文件上传图片
+func (j *JPushClient) UploadImageByFile(r *ImageFileRequest) (*ImageResponse, error) {
+	return j.sendImageFileRequest(http.MethodPost, HOST_IMAGES+"/byfiles", r)
+}
+
+// UpdateImageByFile 通过图片文件更新已上传的图片
+func (j *JPushClient) UpdateImageByFile(mediaID MediaID, r *ImageFileRequest) (*ImageResponse, error) {
+	if !mediaID.Valid() {
+		return nil, errors.New("invalid media id")
+	}
+	return j.sendImageFileRequest(http.MethodPut, HOST_IMAGES+"/byfiles/"+url.PathEscape(mediaID.String()), r)
+}
+
+// sendImageUrlRequest sends an image url request and returns the typed response
+func (j *JPushClient) sendImageUrlRequest(method, url string, r *ImageUrlRequest) (*ImageResponse, error) {
+	if r == nil {
+		return nil, errors.New("image request is nil")
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := j.newRequest(method, url)
+	req.SetBody(body)
+
+	resp := &ImageResponse{}
+	if err := sendJSON(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sendImageFileRequest sends a multipart image file request and returns the typed response
+func (j *JPushClient) sendImageFileRequest(method, url string, r *ImageFileRequest) (*ImageResponse, error) {
+	if r == nil {
+		return nil, errors.New("image request is nil")
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	req := j.newRequest(method, url)
+	req.SetMultipartField("image_type", strconv.Itoa(int(r.ImageType)))
+	for field, f := range r.files() {
+		req.SetMultipartFile(field, f.Name, f.ContentType, f.Reader)
+	}
+
+	resp := &ImageResponse{}
+	if err := sendJSON(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
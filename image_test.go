@@ -0,0 +1,151 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMediaID(t *testing.T) {
+	for id, valid := range map[MediaID]bool{
+		"jgmedia-2-14b23451-0001-41e8-8542-2b5ecb6bc9a5": true,
+		"jgmedia-":                     false,
+		"":                             false,
+		"https://example.com/icon.png": false,
+	} {
+		if id.Valid() != valid {
+			t.Errorf("MediaID(%q).Valid() = %v, want %v", id, !valid, valid)
+		}
+	}
+
+	id := MediaID("jgmedia-1-abc")
+	var a AndroidNotification
+	if err := a.SetBigPicMedia(id); err != nil || a.BigPicPath != "jgmedia-1-abc" || a.Style != 3 {
+		t.Fatalf("android notification = %+v, %v", a, err)
+	}
+	if err := a.SetLargeIconMedia("icon.png"); err == nil || a.LargeIcon != "" {
+		t.Fatal("an invalid media id should be rejected")
+	}
+
+	var o ThirdPartyOptions
+	if err := o.SetLargeIconMedia(id); err != nil || o.LargeIcon != "jgmedia-1-abc" {
+		t.Fatalf("third party options = %+v, %v", o, err)
+	}
+	if err := o.SetSmallIconMedia(id); err != nil || o.SmallIconUri != "jgmedia-1-abc" {
+		t.Fatalf("third party options = %+v, %v", o, err)
+	}
+	if err := o.SetBigPicMedia(""); err == nil {
+		t.Fatal("an empty media id should be rejected")
+	}
+}
+
+func TestImageByUrl(t *testing.T) {
+	var method, path, user string
+	var sent map[string]interface{}
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"media_id":"jgmedia-1-abc","xiaomi_image_url":"https://xm/icon.png"}`, func(req *http.Request) {
+		method, path = req.Method, req.URL.Path
+		user, _, _ = req.BasicAuth()
+		data, _ := io.ReadAll(req.Body)
+		sent = nil
+		_ = json.Unmarshal(data, &sent)
+	}))
+
+	ret, err := c.UploadImageByUrl(&ImageUrlRequest{ImageType: IMAGE_LARGE_ICON, ImageUrl: "https://example.com/icon.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost || path != "/v3/images/byurls" || user != "key" {
+		t.Fatalf("request = %s %s as %s", method, path, user)
+	}
+	if sent["image_type"] != 1.0 || sent["image_url"] != "https://example.com/icon.png" || sent["xiaomi_image_url"] != nil {
+		t.Fatalf("sent = %v", sent)
+	}
+	if ret.MediaID != "jgmedia-1-abc" || ret.XiaomiImageUrl != "https://xm/icon.png" {
+		t.Fatalf("response = %+v", ret)
+	}
+
+	if _, err := c.UpdateImageByUrl(ret.MediaID, &ImageUrlRequest{ImageType: IMAGE_BIG_PICTURE, OppoImageUrl: "https://example.com/big.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/v3/images/byurls/jgmedia-1-abc" || sent["oppo_image_url"] != "https://example.com/big.png" {
+		t.Fatalf("request = %s %s %v", method, path, sent)
+	}
+
+	// invalid requests are rejected before sending
+	method = ""
+	for _, r := range []*ImageUrlRequest{nil, {ImageType: 3, ImageUrl: "u"}, {ImageType: IMAGE_LARGE_ICON}} {
+		if _, err := c.UploadImageByUrl(r); err == nil {
+			t.Fatalf("request %+v should be rejected", r)
+		}
+	}
+	if _, err := c.UpdateImageByUrl("media", &ImageUrlRequest{ImageType: IMAGE_LARGE_ICON, ImageUrl: "u"}); err == nil {
+		t.Fatal("an invalid media id should be rejected")
+	}
+	if method != "" {
+		t.Fatal("invalid requests should not be sent")
+	}
+}
+
+func TestImageByFile(t *testing.T) {
+	var method, path string
+	fields := map[string]string{}
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"media_id":"jgmedia-1-abc"}`, func(req *http.Request) {
+		method, path = req.Method, req.URL.Path
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+			return
+		}
+		for k, v := range req.MultipartForm.Value {
+			fields[k] = v[0]
+		}
+		for k, files := range req.MultipartForm.File {
+			f, _ := files[0].Open()
+			data, _ := io.ReadAll(f)
+			f.Close()
+			fields[k] = files[0].Filename + ":" + files[0].Header.Get("Content-Type") + ":" + string(data)
+		}
+	}))
+
+	ret, err := c.UploadImageByFile(&ImageFileRequest{
+		ImageType:   IMAGE_BIG_PICTURE,
+		JiguangFile: &ImageFile{Name: "big.png", ContentType: "image/png", Reader: strings.NewReader("png")},
+		XiaomiFile:  &ImageFile{Name: "big.jpg", Reader: strings.NewReader("jpg")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost || path != "/v3/images/byfiles" || ret.MediaID != "jgmedia-1-abc" {
+		t.Fatalf("request = %s %s, response = %+v", method, path, ret)
+	}
+	if fields["image_type"] != "2" || fields["jiguang_file"] != "big.png:image/png:png" ||
+		fields["xiaomi_file"] != "big.jpg:application/octet-stream:jpg" || fields["oppo_file"] != "" {
+		t.Fatalf("fields = %v", fields)
+	}
+
+	if _, err := c.UpdateImageByFile("jgmedia-1-abc", &ImageFileRequest{ImageType: IMAGE_LARGE_ICON, FcmFile: &ImageFile{Name: "icon.png", Reader: strings.NewReader("icon")}}); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/v3/images/byfiles/jgmedia-1-abc" || fields["fcm_file"] == "" {
+		t.Fatalf("request = %s %s, fields = %v", method, path, fields)
+	}
+
+	if _, err := c.UploadImageByFile(&ImageFileRequest{ImageType: IMAGE_LARGE_ICON, HuaweiFile: &ImageFile{Name: "empty"}}); err == nil {
+		t.Fatal("a request without file contents should be rejected")
+	}
+}
+
+func TestImageError(t *testing.T) {
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusBadRequest, `{"error":{"code":1003,"message":"image size exceeds the limit"}}`, nil))
+
+	_, err := c.UploadImageByUrl(&ImageUrlRequest{ImageType: IMAGE_LARGE_ICON, ImageUrl: "https://example.com/icon.png"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != 1003 {
+		t.Fatalf("err = %v, want an *APIError", err)
+	}
+}
@@ -0,0 +1,320 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrUnknownLane = errors.New("jpush: unknown priority lane")
+
+// LANE_FALLBACK_WINDOW 频率限制窗口结束后未收到新的频率限制信息时，假定的下一个窗口长度
+const LANE_FALLBACK_WINDOW = time.Second
+
+// LaneOptions 优先级通道参数
+type LaneOptions struct {
+	Name        string  // 通道名称
+	Share       float64 // 为该通道保留的频率限制配额比例，范围 [0, 1]，所有通道之和不超过 1
+	Concurrency int     // 通道内的最大并发请求数，0 表示不限制
+	QueueSize   int     // 通道内最多等待的请求数，0 表示不限制，超出时返回 ErrQueueFull
+}
+
+// LanesOptions 优先级通道配置
+type LanesOptions struct {
+	Lanes   []LaneOptions // 按优先级从高到低排列的通道
+	Default string        // 未指定通道的推送使用的通道，默认为优先级最低的通道
+}
+
+// LaneState 优先级通道的当前状态
+type LaneState struct {
+	Name     string // 通道名称
+	Queued   int    // 等待发送的请求数
+	InFlight int    // 发送中的请求数
+	Used     int    // 当前频率限制窗口内已发送的请求数
+	Reserved int    // 当前频率限制窗口内为该通道保留的请求数
+}
+
+type laneContextKey struct{}
+
+// WithLane 返回指定推送通道的 ctx，用于 PushToMany 等接收 ctx 的推送方法
+func WithLane(ctx context.Context, lane string) context.Context {
+	return context.WithValue(ctx, laneContextKey{}, lane)
+}
+
+func laneFromContext(ctx context.Context) string {
+	lane, _ := ctx.Value(laneContextKey{}).(string)
+	return lane
+}
+
+// EnableLanes 为客户端的推送启用优先级通道。每个通道有独立的等待队列与并发数，
+// 并按 Share 保留频率限制配额：低优先级通道只能使用其它通道保留之外的配额，
+// 因此大批量的营销推送不会占满事务类推送的配额。需要在发送请求前调用。
+func (j *JPushClient) EnableLanes(opts *LanesOptions) error {
+	s, err := newLaneScheduler(opts)
+	if err != nil {
+		return err
+	}
+	j.lanes = s
+	return nil
+}
+
+// PushWithLane 通过指定的优先级通道推送消息，返回值与 Push 相同
+func (j *JPushClient) PushWithLane(lane string, data []byte) (string, error) {
+	return j.pushFront(WithLane(context.Background(), lane), data)
+}
+
+// Lanes 返回各优先级通道的当前状态，未启用优先级通道时返回 nil
+func (j *JPushClient) Lanes() []LaneState {
+	if j.lanes == nil {
+		return nil
+	}
+	return j.lanes.states()
+}
+
+type lane struct {
+	LaneOptions
+	inflight int
+	used     int           // requests admitted in the current rate limit window
+	queue    []*laneWaiter // waiters by arrival
+}
+
+// laneWaiter is a request queued in a lane
+type laneWaiter struct {
+	ready  chan struct{} // closed when admitted
+	window int           // rate limit window the request was admitted in
+}
+
+// busy reports whether the lane is at its concurrency
+func (l *lane) busy() bool {
+	return l.Concurrency > 0 && l.inflight >= l.Concurrency
+}
+
+// laneScheduler admits push requests lane by lane, keeping each lane within its concurrency
+// and the rate limit share reserved for the other lanes
+type laneScheduler struct {
+	mu        sync.Mutex
+	lanes     []*lane // by priority, highest first
+	byName    map[string]*lane
+	def       *lane
+	limit     int       // requests per window, 0 until JPush reports it
+	remaining int       // requests left in the current window
+	resetAt   time.Time // end of the current window
+	window    int       // counts the windows started by roll and update
+	timer     *time.Timer
+}
+
+func newLaneScheduler(opts *LanesOptions) (*laneScheduler, error) {
+	if opts == nil || len(opts.Lanes) == 0 {
+		return nil, errors.New("no lanes configured")
+	}
+
+	s := &laneScheduler{byName: make(map[string]*lane)}
+	var share float64
+	for _, o := range opts.Lanes {
+		if o.Name == "" {
+			return nil, errors.New("lane name is empty")
+		}
+		if _, ok := s.byName[o.Name]; ok {
+			return nil, fmt.Errorf("duplicate lane %q", o.Name)
+		}
+		if o.Share < 0 || o.Share > 1 {
+			return nil, fmt.Errorf("lane %q share must be between 0 and 1", o.Name)
+		}
+		if o.Concurrency < 0 {
+			return nil, fmt.Errorf("lane %q concurrency must not be negative", o.Name)
+		}
+		share += o.Share
+
+		l := &lane{LaneOptions: o}
+		s.lanes = append(s.lanes, l)
+		s.byName[o.Name] = l
+	}
+	if share > 1 {
+		return nil, errors.New("lane shares add up to more than 1")
+	}
+
+	s.def = s.lanes[len(s.lanes)-1]
+	if opts.Default != "" {
+		l, ok := s.byName[opts.Default]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownLane, opts.Default)
+		}
+		s.def = l
+	}
+	return s, nil
+}
+
+// acquire waits until the lane may send one request
+func (s *laneScheduler) acquire(ctx context.Context, name string) (*lane, error) {
+	s.mu.Lock()
+
+	l := s.def
+	if name != "" {
+		var ok bool
+		if l, ok = s.byName[name]; !ok {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s", ErrUnknownLane, name)
+		}
+	}
+
+	if len(l.queue) == 0 && s.admit(l) {
+		s.mu.Unlock()
+		return l, nil
+	}
+	if l.QueueSize > 0 && len(l.queue) >= l.QueueSize {
+		s.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	w := &laneWaiter{ready: make(chan struct{})}
+	l.queue = append(l.queue, w)
+	s.dispatch() // arms the reset timer when the lane waits for the rate limit
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, q := range l.queue {
+		if q == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return nil, ctx.Err()
+		}
+	}
+	// admitted while giving up, hand the slot back, the quota only when its window is still current
+	l.inflight--
+	if w.window == s.window {
+		l.used--
+		s.remaining++
+	}
+	s.dispatch()
+	return nil, ctx.Err()
+}
+
+// release frees the concurrency slot of a finished request
+func (s *laneScheduler) release(l *lane) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l.inflight--
+	s.dispatch()
+}
+
+// update records the rate limit reported by a push response
+func (s *laneScheduler) update(resp *apiResponse) {
+	rl := parseRateLimit(resp.Header)
+	if rl == nil || rl.Limit <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limit = rl.Limit
+	s.remaining = rl.Remaining
+	s.resetAt = time.Now().Add(rl.Reset)
+	s.window++
+	s.dispatch()
+}
+
+// admit takes a slot of l when its concurrency and the rate limit allow it, s.mu must be held
+func (s *laneScheduler) admit(l *lane) bool {
+	if l.busy() {
+		return false
+	}
+
+	s.roll()
+	if s.limit > 0 && l.used >= s.reserved(l) {
+		// beyond its own reservation the lane may only use what the other lanes do not reserve
+		free := s.remaining
+		for _, o := range s.lanes {
+			if o != l {
+				free -= max(0, s.reserved(o)-o.used)
+			}
+		}
+		if free <= 0 {
+			return false
+		}
+	}
+
+	l.inflight++
+	l.used++
+	s.remaining--
+	return true
+}
+
+// reserved returns the requests reserved for l in one window
+func (s *laneScheduler) reserved(l *lane) int {
+	return int(math.Ceil(l.Share * float64(s.limit)))
+}
+
+// roll starts a new rate limit window once the current one has ended, s.mu must be held.
+// The new window ends after LANE_FALLBACK_WINDOW unless a response reports its end,
+// so a lane out of quota is retried even when no more rate limit headers arrive.
+func (s *laneScheduler) roll() {
+	now := time.Now()
+	if s.resetAt.IsZero() || now.Before(s.resetAt) {
+		return
+	}
+	for _, l := range s.lanes {
+		l.used = 0
+	}
+	s.remaining = s.limit
+	s.resetAt = now.Add(LANE_FALLBACK_WINDOW)
+	s.window++
+}
+
+// dispatch admits queued requests by lane priority, s.mu must be held
+func (s *laneScheduler) dispatch() {
+	s.roll()
+
+	rateLimited := false
+	for _, l := range s.lanes {
+		for len(l.queue) > 0 && s.admit(l) {
+			l.queue[0].window = s.window
+			close(l.queue[0].ready)
+			l.queue = l.queue[1:]
+		}
+		// lanes at their concurrency are dispatched again by release
+		if len(l.queue) > 0 && !l.busy() {
+			rateLimited = true
+		}
+	}
+
+	// requests held back by the rate limit are admitted once the window resets
+	if rateLimited && time.Now().Before(s.resetAt) && s.timer == nil {
+		s.timer = time.AfterFunc(time.Until(s.resetAt), func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.timer = nil
+			s.dispatch()
+		})
+	}
+}
+
+// states returns a snapshot of every lane
+func (s *laneScheduler) states() []LaneState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roll()
+	states := make([]LaneState, len(s.lanes))
+	for i, l := range s.lanes {
+		states[i] = LaneState{
+			Name:     l.Name,
+			Queued:   len(l.queue),
+			InFlight: l.inflight,
+			Used:     l.used,
+			Reserved: s.reserved(l),
+		}
+	}
+	return states
+}
@@ -0,0 +1,235 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func rateLimitResponse(limit, remaining, reset string) *apiResponse {
+	h := http.Header{}
+	h.Set(HEADER_RATE_LIMIT_LIMIT, limit)
+	h.Set(HEADER_RATE_LIMIT_REMAINING, remaining)
+	h.Set(HEADER_RATE_LIMIT_RESET, reset)
+	return &apiResponse{StatusCode: http.StatusOK, Header: h}
+}
+
+func TestLaneSchedulerReservesShare(t *testing.T) {
+	s, err := newLaneScheduler(&LanesOptions{Lanes: []LaneOptions{
+		{Name: "transactional", Share: 0.5, Concurrency: 4},
+		{Name: "marketing", Concurrency: 4},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.update(rateLimitResponse("4", "4", "60"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// marketing may only use the half of the quota not reserved for transactional pushes
+	for i := 0; i < 2; i++ {
+		l, err := s.acquire(ctx, "")
+		if err != nil {
+			t.Fatalf("marketing push %d: %v", i, err)
+		}
+		s.release(l)
+	}
+	if _, err := s.acquire(ctx, "marketing"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("marketing push beyond its share: err = %v, want it held back", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		l, err := s.acquire(context.Background(), "transactional")
+		if err != nil {
+			t.Fatalf("transactional push %d: %v", i, err)
+		}
+		s.release(l)
+	}
+
+	st := s.states()
+	if st[0].Used != 2 || st[1].Used != 2 || st[1].Queued != 0 {
+		t.Fatalf("states = %+v", st)
+	}
+}
+
+func TestLaneSchedulerConcurrency(t *testing.T) {
+	s, err := newLaneScheduler(&LanesOptions{
+		Lanes:   []LaneOptions{{Name: "otp", Concurrency: 1, QueueSize: 1}, {Name: "bulk"}},
+		Default: "bulk",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.acquire(context.Background(), "otp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admitted := make(chan *lane)
+	go func() {
+		l, _ := s.acquire(context.Background(), "otp")
+		admitted <- l
+	}()
+
+	for s.states()[0].Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := s.acquire(context.Background(), "otp"); err != ErrQueueFull {
+		t.Fatalf("err = %v, want ErrQueueFull", err)
+	}
+	// other lanes are not blocked by a busy lane
+	bulk, err := s.acquire(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.release(bulk)
+
+	s.release(first)
+	select {
+	case l := <-admitted:
+		s.release(l)
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued request was not admitted after release")
+	}
+
+	if _, err := s.acquire(context.Background(), "unknown"); !errors.Is(err, ErrUnknownLane) {
+		t.Fatalf("err = %v, want ErrUnknownLane", err)
+	}
+}
+
+func TestNewLaneSchedulerValidates(t *testing.T) {
+	_, err := newLaneScheduler(&LanesOptions{Lanes: []LaneOptions{
+		{Name: "a", Share: 0.7},
+		{Name: "b", Share: 0.7},
+	}})
+	if err == nil {
+		t.Fatal("shares above 1 should be rejected")
+	}
+}
+
+func TestLaneSchedulerNoTimerWhenBlockedByConcurrency(t *testing.T) {
+	s, err := newLaneScheduler(&LanesOptions{Lanes: []LaneOptions{{Name: "otp", Concurrency: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.acquire(context.Background(), "otp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admitted := make(chan *lane)
+	go func() {
+		l, _ := s.acquire(context.Background(), "otp")
+		admitted <- l
+	}()
+	for s.states()[0].Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the window ends at once, the waiter is only blocked by the lane concurrency
+	s.update(rateLimitResponse("100", "99", "0"))
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	timer := s.timer
+	s.mu.Unlock()
+	if timer != nil {
+		t.Fatalf("timer = %v: a waiter blocked by concurrency should not arm the reset timer", timer)
+	}
+
+	s.release(first)
+	select {
+	case l := <-admitted:
+		s.release(l)
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued request was not admitted after release")
+	}
+}
+
+func TestLaneSchedulerUnboundedByDefault(t *testing.T) {
+	s, err := newLaneScheduler(&LanesOptions{Lanes: []LaneOptions{{Name: "bulk"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		if _, err := s.acquire(ctx, ""); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	if st := s.states(); st[0].InFlight != 10 {
+		t.Fatalf("states = %+v", st)
+	}
+}
+
+func TestLaneSchedulerRetriesWithoutRateLimitHeaders(t *testing.T) {
+	s, err := newLaneScheduler(&LanesOptions{Lanes: []LaneOptions{{Name: "bulk"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the window has ended, the next one starts with the first request
+	s.update(rateLimitResponse("1", "1", "0"))
+
+	l, err := s.acquire(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.release(l)
+
+	// the quota is used up and no response reports the next window
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	l, err = s.acquire(ctx, "")
+	if err != nil {
+		t.Fatalf("queued request was not admitted in a new window: %v", err)
+	}
+	s.release(l)
+	if elapsed := time.Since(start); elapsed < LANE_FALLBACK_WINDOW/2 {
+		t.Fatalf("admitted after %v, want it held until the window ends", elapsed)
+	}
+}
+
+func TestLaneSchedulerCancelAfterRoll(t *testing.T) {
+	s, err := newLaneScheduler(&LanesOptions{Lanes: []LaneOptions{{Name: "bulk"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.update(rateLimitResponse("2", "0", "60"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := s.acquire(ctx, "")
+		done <- err
+	}()
+	for s.states()[0].Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the waiter gives up, is admitted before it takes the lock and a new window starts
+	s.mu.Lock()
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	s.remaining = 1
+	s.dispatch()
+	s.resetAt = time.Now()
+	s.roll()
+	s.mu.Unlock()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	s.mu.Lock()
+	remaining := s.remaining
+	s.mu.Unlock()
+	if remaining != 2 {
+		t.Fatalf("remaining = %d, want the limit of the new window", remaining)
+	}
+}
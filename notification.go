@@ -1,5 +1,7 @@
 package jpush
 
+import "errors"
+
 type Notification struct {
 	AiOpportunity bool                   `json:"ai_opportunity,omitempty"` // 如需采用“智能时机”策略下发通知，必须指定该字段。
 	Alert         string                 `json:"alert,omitempty"`          // 通知的内容在各个平台上，都可能只有这一个最基本的属性 "alert"。
@@ -96,3 +98,22 @@ func (n *Notification) SetWinPhone(winPhone *WinPhoneNotification) {
 func (n *Notification) SetVoip(value map[string]interface{}) {
 	n.Voip = value
 }
+
+// SetLargeIconMedia 使用图片 API 返回的 media_id 设置通知栏大图标
+func (a *AndroidNotification) SetLargeIconMedia(id MediaID) error {
+	if !id.Valid() {
+		return errors.New("invalid media id")
+	}
+	a.LargeIcon = id.String()
+	return nil
+}
+
+// SetBigPicMedia 使用图片 API 返回的 media_id 设置大图片，并切换为大图片样式（style = 3）
+func (a *AndroidNotification) SetBigPicMedia(id MediaID) error {
+	if !id.Valid() {
+		return errors.New("invalid media id")
+	}
+	a.Style = 3
+	a.BigPicPath = id.String()
+	return nil
+}
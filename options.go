@@ -1,5 +1,10 @@
 package jpush
 
+import (
+	"encoding/json"
+	"errors"
+)
+
 type Options struct {
 	SendNo            int               `json:"sendno,omitempty"`              //推送序号
 	TimeToLive        int               `json:"time_to_live,omitempty"`        //离线消息保留时长(秒)
@@ -8,6 +13,80 @@ type Options struct {
 	ApnsCollapseId    string            `json:"apns_collapse_id,omitempty"`    //更新 iOS 通知的标识符
 	BigPushDuration   int               `json:"big_push_duration,omitempty"`   //定速推送时长(分钟)
 	ThirdPartyChannel ThirdPartyChannel `json:"third_party_channel,omitempty"` //推送请求下发通道
+
+	explicit optionFields // 通过 Set 方法显式设置的字段，零值也会序列化
+}
+
+// optionFields is a set of the Options fields that were set explicitly
+type optionFields uint8
+
+const (
+	optionSendNo optionFields = 1 << iota
+	optionTimeToLive
+	optionOverrideMsgId
+	optionApnsProduction
+	optionBigPushDuration
+)
+
+var optionKeys = map[string]optionFields{
+	"sendno":            optionSendNo,
+	"time_to_live":      optionTimeToLive,
+	"override_msg_id":   optionOverrideMsgId,
+	"apns_production":   optionApnsProduction,
+	"big_push_duration": optionBigPushDuration,
+}
+
+// MarshalJSON 序列化推送选项，零值字段只有通过 Set 方法显式设置时才会输出，
+// 未输出的字段在发送时使用客户端的默认推送选项
+func (o Options) MarshalJSON() ([]byte, error) {
+	intField := func(v int, f optionFields) *int {
+		if v == 0 && o.explicit&f == 0 {
+			return nil
+		}
+		return &v
+	}
+	var apnsProduction *bool
+	if o.ApnsProduction || o.explicit&optionApnsProduction != 0 {
+		apnsProduction = &o.ApnsProduction
+	}
+
+	return json.Marshal(struct {
+		SendNo            *int              `json:"sendno,omitempty"`
+		TimeToLive        *int              `json:"time_to_live,omitempty"`
+		OverrideMsgId     *int              `json:"override_msg_id,omitempty"`
+		ApnsProduction    *bool             `json:"apns_production,omitempty"`
+		ApnsCollapseId    string            `json:"apns_collapse_id,omitempty"`
+		BigPushDuration   *int              `json:"big_push_duration,omitempty"`
+		ThirdPartyChannel ThirdPartyChannel `json:"third_party_channel,omitempty"`
+	}{
+		SendNo:            intField(o.SendNo, optionSendNo),
+		TimeToLive:        intField(o.TimeToLive, optionTimeToLive),
+		OverrideMsgId:     intField(o.OverrideMsgId, optionOverrideMsgId),
+		ApnsProduction:    apnsProduction,
+		ApnsCollapseId:    o.ApnsCollapseId,
+		BigPushDuration:   intField(o.BigPushDuration, optionBigPushDuration),
+		ThirdPartyChannel: o.ThirdPartyChannel,
+	})
+}
+
+// UnmarshalJSON 反序列化推送选项，JSON 中出现的字段视为显式设置
+func (o *Options) UnmarshalJSON(data []byte) error {
+	type options Options
+	var v options
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+
+	*o = Options(v)
+	o.explicit = 0
+	for k := range keys {
+		o.explicit |= optionKeys[k]
+	}
+	return nil
 }
 
 type ThirdChannelType string
@@ -23,6 +102,7 @@ const (
 	OPPO   ThirdChannelType = "oppo"
 	VIVO   ThirdChannelType = "vivo"
 	FCM    ThirdChannelType = "fcm"
+	HONOR  ThirdChannelType = "honor"
 )
 
 type ThirdPartyOptions struct {
@@ -52,26 +132,31 @@ type ThirdPartyChannel map[string]ThirdPartyOptions
 // SetSendNo 设置消息的发送编号，用来覆盖推送时由 JPush 生成的编号。
 func (o *Options) SetSendNo(sendNo int) {
 	o.SendNo = sendNo
+	o.explicit |= optionSendNo
 }
 
-// SetTimeToLive 设置消息的有效期，单位为秒。
+// SetTimeToLive 设置消息的有效期，单位为秒，显式设置的 0 表示不保留离线消息。
 func (o *Options) SetTimeToLive(timeToLive int) {
 	o.TimeToLive = timeToLive
+	o.explicit |= optionTimeToLive
 }
 
 // SetOverrideMsgId 设置覆盖推送时由 JPush 生成的消息 ID。
 func (o *Options) SetOverrideMsgId(overrideMsgId int) {
 	o.OverrideMsgId = overrideMsgId
+	o.explicit |= optionOverrideMsgId
 }
 
-// SetApnsProduction 设置推送时 APNs 是否生产环境。
+// SetApnsProduction 设置推送时 APNs 是否生产环境，显式设置的 false 不会被客户端的默认推送选项覆盖。
 func (o *Options) SetApnsProduction(apnsProduction bool) {
 	o.ApnsProduction = apnsProduction
+	o.explicit |= optionApnsProduction
 }
 
 // SetBigPushDuration 设置大推送时长，单位为秒。
 func (o *Options) SetBigPushDuration(bigPushDuration int) {
 	o.BigPushDuration = bigPushDuration
+	o.explicit |= optionBigPushDuration
 }
 
 // AddThirdPartyChannel 添加第三方渠道。
@@ -81,3 +166,31 @@ func (o *Options) AddThirdPartyChannel(channel ThirdChannelType, value ThirdPart
 	}
 	o.ThirdPartyChannel[channel.String()] = value
 }
+
+// SetLargeIconMedia 使用图片 API 返回的 media_id 设置厂商消息大图标，小米/OPPO 通道需预先上传图片。
+func (t *ThirdPartyOptions) SetLargeIconMedia(id MediaID) error {
+	if !id.Valid() {
+		return errors.New("invalid media id")
+	}
+	t.LargeIcon = id.String()
+	return nil
+}
+
+// SetSmallIconMedia 使用图片 API 返回的 media_id 设置厂商消息小图标，小米通道需预先上传图片。
+func (t *ThirdPartyOptions) SetSmallIconMedia(id MediaID) error {
+	if !id.Valid() {
+		return errors.New("invalid media id")
+	}
+	t.SmallIconUri = id.String()
+	return nil
+}
+
+// SetBigPicMedia 使用图片 API 返回的 media_id 设置厂商消息大图片，并切换为大图片样式（style = 3），小米/OPPO 通道需预先上传图片。
+func (t *ThirdPartyOptions) SetBigPicMedia(id MediaID) error {
+	if !id.Valid() {
+		return errors.New("invalid media id")
+	}
+	t.Style = 3
+	t.BigPicPath = id.String()
+	return nil
+}
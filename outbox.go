@@ -0,0 +1,843 @@
+package jpush
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OUTBOX_SEGMENT_SIZE  = 64 << 20         // 日志分段默认大小
+	OUTBOX_DEAD_LETTER   = "deadletter.log" // 死信文件名
+	OUTBOX_LOCK          = "LOCK"           // 锁文件名，同一时间只有一个进程可以打开发件箱
+	outboxSegmentPrefix  = "outbox-"
+	outboxSegmentSuffix  = ".log"
+	outboxMaxRetryPeriod = 5 * time.Minute
+
+	outboxOpPut  = "put"  // payload persisted
+	outboxOpCid  = "cid"  // cid assigned before the first attempt
+	outboxOpFail = "fail" // transient failure, will be retried
+	outboxOpDone = "done" // pushed successfully
+	outboxOpDead = "dead" // moved to the dead letter file
+)
+
+var (
+	ErrOutboxClosed = errors.New("jpush: outbox is closed")
+	ErrOutboxLocked = errors.New("jpush: outbox is opened by another process")
+)
+
+// OutboxOptions 持久化发件箱参数，零值字段使用默认值
+type OutboxOptions struct {
+	SegmentSize   int64                                       // 日志分段的最大字节数，超过后轮转并压缩，默认 64MB
+	MaxAttempts   int                                         // 最大发送次数，超过后移入死信文件，默认 5
+	RetryInterval time.Duration                               // 首次重试间隔，之后每次翻倍，最长 5 分钟，默认 5 秒
+	OnResult      func(id string, ret *PushResult, err error) // 可选，推送成功或移入死信文件时回调
+}
+
+// OutboxEntry 发件箱中的推送记录
+type OutboxEntry struct {
+	ID      string          `json:"id"`                // 记录 ID
+	Cid     string          `json:"cid,omitempty"`     // 推送使用的 cid，重试与重放时保持不变
+	Payload json.RawMessage `json:"payload,omitempty"` // 推送内容
+	Attempt int             `json:"attempt,omitempty"` // 已失败的发送次数
+	MsgID   MsgID           `json:"msg_id,omitempty"`  // 推送成功后的消息 ID
+	Error   string          `json:"error,omitempty"`   // 最近一次失败的原因
+	Time    time.Time       `json:"time"`              // 记录时间
+}
+
+// outboxRecord is one line of a segment or of the dead letter file
+type outboxRecord struct {
+	Op string `json:"op"`
+	OutboxEntry
+}
+
+type outboxEntry struct {
+	seq     uint64
+	id      string
+	cid     string
+	payload json.RawMessage
+	attempt int
+	next    time.Time
+	index   int // position in the retry heap, -1 when not waiting for a retry
+}
+
+// outboxRetries orders the entries waiting for a retry by their next attempt
+type outboxRetries []*outboxEntry
+
+func (h outboxRetries) Len() int { return len(h) }
+func (h outboxRetries) Less(i, j int) bool {
+	if h[i].next.Equal(h[j].next) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].next.Before(h[j].next)
+}
+func (h outboxRetries) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *outboxRetries) Push(x any) {
+	e := x.(*outboxEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *outboxRetries) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Outbox 基于本地文件的推送发件箱。推送内容在发送前写入追加式日志，发送结果同样记录在日志中，
+// 重启后未完成的推送会按原顺序重新发送并沿用已分配的 cid，避免重复推送。
+// 多次失败或被 JPush 拒绝的推送会移入死信文件，可通过 ReplayDeadLetters 重新放入发件箱。
+type Outbox struct {
+	dir    string
+	opts   OutboxOptions
+	send   func(ctx context.Context, data []byte) (*PushResult, error)
+	newCid func() (string, error)
+
+	mu       sync.Mutex
+	closed   bool
+	lock     *os.File
+	seg      *os.File
+	segIndex int
+	segSize  int64
+	rotateAt int64
+	seq      uint64
+	entries  map[string]*outboxEntry
+	ready    []*outboxEntry
+	retries  outboxRetries
+	drained  chan struct{}
+	wake     chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// OpenOutbox 打开 dir 下的发件箱并在后台开始发送，日志中未完成的推送会被重新发送。
+// 发件箱目录同一时间只能被一个进程打开，已被打开时返回 ErrOutboxLocked；只查看内容时使用 ReadOutbox。
+func (j *JPushClient) OpenOutbox(dir string, opts *OutboxOptions) (*Outbox, error) {
+	newCid := func() (string, error) {
+		if j.cidPool != nil {
+			return j.cidPool.Get(CID_TYPE_PUSH)
+		}
+		cids, err := j.getCidList(1, CID_TYPE_PUSH)
+		if err != nil {
+			return "", err
+		}
+		if len(cids) == 0 {
+			return "", errors.New("empty cid list")
+		}
+		return cids[0], nil
+	}
+	return openOutbox(dir, opts, j.pushResult, newCid)
+}
+
+func openOutbox(dir string, opts *OutboxOptions, send func(context.Context, []byte) (*PushResult, error), newCid func() (string, error)) (*Outbox, error) {
+	o := OutboxOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.SegmentSize <= 0 {
+		o.SegmentSize = OUTBOX_SEGMENT_SIZE
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = 5 * time.Second
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(filepath.Join(dir, OUTBOX_LOCK), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	b := &Outbox{
+		dir:     dir,
+		lock:    lock,
+		opts:    o,
+		send:    send,
+		newCid:  newCid,
+		entries: make(map[string]*outboxEntry),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	if err := b.loadSegments(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	pending := b.pending()
+	for _, e := range pending {
+		e.index = -1
+	}
+	b.ready = pending
+
+	// start from a compacted segment holding only the unsent entries
+	if err := b.compact(); err != nil {
+		if b.seg != nil {
+			b.seg.Close()
+		}
+		lock.Close()
+		return nil, err
+	}
+
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	go b.run()
+
+	return b, nil
+}
+
+// OutboxSnapshot 发件箱内容的只读快照
+type OutboxSnapshot struct {
+	Pending     []OutboxEntry // 尚未完成的推送，按写入顺序排列
+	DeadLetters []OutboxEntry // 死信文件中的推送
+}
+
+// ReadOutbox 只读地读取 dir 下的发件箱内容，不加锁、不发送也不压缩日志，
+// 可以在发件箱被其它进程打开时用于查看未完成的推送与死信
+func ReadOutbox(dir string) (*OutboxSnapshot, error) {
+	var err error
+	// a segment may be removed by a concurrent compaction, the newer segment has its entries
+	for attempt := 0; attempt < 3; attempt++ {
+		b := &Outbox{dir: dir, entries: make(map[string]*outboxEntry)}
+		if err = b.loadSegments(); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot := &OutboxSnapshot{}
+		for _, e := range b.pending() {
+			snapshot.Pending = append(snapshot.Pending, OutboxEntry{ID: e.id, Cid: e.cid, Payload: e.payload, Attempt: e.attempt})
+		}
+		if snapshot.DeadLetters, err = readDeadLetters(dir); err != nil {
+			return nil, err
+		}
+		return snapshot, nil
+	}
+	return nil, err
+}
+
+// Enqueue 将推送内容写入发件箱，写入成功后返回记录 ID，推送在后台发送
+func (b *Outbox) Enqueue(p *PayLoad) (string, error) {
+	if p == nil {
+		return "", errors.New("payload is nil")
+	}
+	data, err := p.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return b.EnqueueBytes(data)
+}
+
+// EnqueueBytes 将 json 格式的推送内容写入发件箱，内容中已有的 cid 会被沿用
+func (b *Outbox) EnqueueBytes(data []byte) (string, error) {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return "", err
+	}
+	var cid string
+	if raw, ok := body["cid"]; ok {
+		_ = json.Unmarshal(raw, &cid)
+	}
+
+	id, err := newOutboxID()
+	if err != nil {
+		return "", err
+	}
+
+	entry := OutboxEntry{ID: id, Cid: cid, Payload: json.RawMessage(data)}
+	if err := b.put([]OutboxEntry{entry}); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Pending 返回尚未完成的推送数量
+func (b *Outbox) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.entries)
+}
+
+// Drain 等待发件箱中所有推送完成（成功或移入死信文件），ctx 结束时返回 ctx 的错误
+func (b *Outbox) Drain(ctx context.Context) error {
+	b.mu.Lock()
+	if len(b.entries) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	if b.drained == nil {
+		b.drained = make(chan struct{})
+	}
+	drained := b.drained
+	b.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeadLetters 返回死信文件中的推送记录
+func (b *Outbox) DeadLetters() ([]OutboxEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return readDeadLetters(b.dir)
+}
+
+// ReplayDeadLetters 将死信文件中的推送重新放入发件箱并清空死信文件，返回重放的数量。
+// 重放的推送沿用原有的 cid 与记录 ID，失败次数重新计算。
+func (b *Outbox) ReplayDeadLetters() (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dead, err := readDeadLetters(b.dir)
+	if err != nil || len(dead) == 0 {
+		return 0, err
+	}
+
+	for i := range dead {
+		dead[i].Attempt = 0
+		dead[i].Error = ""
+	}
+	if err := b.putLocked(dead); err != nil {
+		return 0, err
+	}
+
+	// the entries are safely in the log, a crash before truncating only replays them twice under the same id
+	if err := os.Truncate(filepath.Join(b.dir, OUTBOX_DEAD_LETTER), 0); err != nil {
+		return len(dead), err
+	}
+	return len(dead), nil
+}
+
+// Close 停止发送并关闭日志文件，发送中的推送会被取消并在下次打开时重新发送
+func (b *Outbox) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrOutboxClosed
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	b.cancel()
+	<-b.done
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.seg.Close()
+	// closing the file releases the lock
+	if lerr := b.lock.Close(); err == nil {
+		err = lerr
+	}
+	return err
+}
+
+// put persists new entries and queues them for sending
+func (b *Outbox) put(entries []OutboxEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.putLocked(entries)
+}
+
+func (b *Outbox) putLocked(entries []OutboxEntry) error {
+	if b.closed {
+		return ErrOutboxClosed
+	}
+
+	now := time.Now()
+	records := make([]*outboxRecord, len(entries))
+	for i := range entries {
+		entries[i].Time = now
+		records[i] = &outboxRecord{Op: outboxOpPut, OutboxEntry: entries[i]}
+	}
+	if err := b.append(records...); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if e, ok := b.entries[r.ID]; ok && e.index >= 0 {
+			heap.Remove(&b.retries, e.index)
+		}
+		e := b.apply(r)
+		e.index = -1
+		b.ready = append(b.ready, e)
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// run sends the queued entries one by one, in the order they were enqueued
+func (b *Outbox) run() {
+	defer close(b.done)
+
+	for {
+		e, wait := b.next()
+		if e != nil {
+			b.deliver(e)
+			continue
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-b.wake:
+		case <-timeout:
+		case <-b.ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if b.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// next returns the next entry to send, or how long to wait for a retry when none is ready
+func (b *Outbox) next() (*outboxEntry, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ctx.Err() != nil {
+		return nil, 0
+	}
+
+	now := time.Now()
+	for len(b.retries) > 0 && !b.retries[0].next.After(now) {
+		b.ready = append(b.ready, heap.Pop(&b.retries).(*outboxEntry))
+	}
+
+	for len(b.ready) > 0 {
+		e := b.ready[0]
+		b.ready[0] = nil
+		b.ready = b.ready[1:]
+		if b.entries[e.id] == e {
+			return e, 0
+		}
+	}
+
+	if len(b.retries) > 0 {
+		return nil, time.Until(b.retries[0].next)
+	}
+	return nil, 0
+}
+
+// deliver sends one entry and records its outcome
+func (b *Outbox) deliver(e *outboxEntry) {
+	if e.cid == "" {
+		cid, err := b.newCid()
+		if err != nil {
+			b.fail(e, err)
+			return
+		}
+		b.mu.Lock()
+		err = b.append(&outboxRecord{Op: outboxOpCid, OutboxEntry: OutboxEntry{ID: e.id, Cid: cid, Time: time.Now()}})
+		if err == nil {
+			e.cid = cid
+		}
+		b.mu.Unlock()
+		if err != nil {
+			b.fail(e, err)
+			return
+		}
+	}
+
+	data, err := setCid(e.payload, e.cid)
+	if err != nil {
+		b.bury(e, err)
+		return
+	}
+
+	ret, err := b.send(b.ctx, data)
+	if err != nil && b.ctx.Err() != nil {
+		// closing, the entry stays in the log and is sent again on the next start
+		return
+	}
+	if err != nil {
+		b.fail(e, err)
+		return
+	}
+
+	b.mu.Lock()
+	// if the outcome is lost the entry is sent again with the same cid, which JPush does not push twice
+	_ = b.append(&outboxRecord{Op: outboxOpDone, OutboxEntry: OutboxEntry{ID: e.id, MsgID: ret.MsgID, Time: time.Now()}})
+	b.remove(e)
+	b.mu.Unlock()
+
+	if b.opts.OnResult != nil {
+		b.opts.OnResult(e.id, ret, nil)
+	}
+}
+
+// fail schedules a retry, or buries the entry when the failure is permanent or attempts are exhausted
+func (b *Outbox) fail(e *outboxEntry, err error) {
+	if isPermanentPushError(err) || e.attempt+1 >= b.opts.MaxAttempts {
+		b.bury(e, err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e.attempt++
+	rec := &outboxRecord{Op: outboxOpFail, OutboxEntry: OutboxEntry{ID: e.id, Attempt: e.attempt, Error: err.Error(), Time: time.Now()}}
+	if err := b.append(rec); err != nil {
+		// the log is unusable, leave the entry for the next start
+		return
+	}
+
+	delay := b.opts.RetryInterval << (e.attempt - 1)
+	if delay <= 0 || delay > outboxMaxRetryPeriod {
+		delay = outboxMaxRetryPeriod
+	}
+	e.next = time.Now().Add(delay)
+	heap.Push(&b.retries, e)
+}
+
+// bury moves the entry to the dead letter file
+func (b *Outbox) bury(e *outboxEntry, cause error) {
+	b.mu.Lock()
+
+	now := time.Now()
+	dead := OutboxEntry{ID: e.id, Cid: e.cid, Payload: e.payload, Attempt: e.attempt + 1, Error: cause.Error(), Time: now}
+	if err := b.appendDeadLetter(&outboxRecord{Op: outboxOpDead, OutboxEntry: dead}); err != nil {
+		b.mu.Unlock()
+		return
+	}
+	_ = b.append(&outboxRecord{Op: outboxOpDead, OutboxEntry: OutboxEntry{ID: e.id, Error: cause.Error(), Time: now}})
+	b.remove(e)
+	b.mu.Unlock()
+
+	if b.opts.OnResult != nil {
+		b.opts.OnResult(e.id, nil, cause)
+	}
+}
+
+// remove forgets a finished entry, b.mu must be held
+func (b *Outbox) remove(e *outboxEntry) {
+	if b.entries[e.id] != e {
+		return
+	}
+	delete(b.entries, e.id)
+
+	if len(b.entries) == 0 && b.drained != nil {
+		close(b.drained)
+		b.drained = nil
+	}
+}
+
+// apply replays one log record into the in-memory state
+func (b *Outbox) apply(r *outboxRecord) *outboxEntry {
+	switch r.Op {
+	case outboxOpPut:
+		e, ok := b.entries[r.ID]
+		if !ok {
+			b.seq++
+			e = &outboxEntry{seq: b.seq, id: r.ID, index: -1}
+			b.entries[r.ID] = e
+		}
+		e.cid = r.Cid
+		e.payload = r.Payload
+		e.attempt = r.Attempt
+		return e
+	case outboxOpCid:
+		if e, ok := b.entries[r.ID]; ok {
+			e.cid = r.Cid
+		}
+	case outboxOpFail:
+		if e, ok := b.entries[r.ID]; ok {
+			e.attempt = r.Attempt
+		}
+	case outboxOpDone, outboxOpDead:
+		delete(b.entries, r.ID)
+	}
+	return nil
+}
+
+// loadSegments replays every segment in ascending order
+func (b *Outbox) loadSegments() error {
+	segments, err := b.segments()
+	if err != nil {
+		return err
+	}
+	for _, index := range segments {
+		if err := b.load(b.segmentPath(index)); err != nil {
+			return err
+		}
+		b.segIndex = index
+	}
+	return nil
+}
+
+// load replays a segment, a torn last line left by a crash is ignored
+func (b *Outbox) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			rec := &outboxRecord{}
+			if json.Unmarshal(line, rec) == nil && rec.ID != "" {
+				b.apply(rec)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// pending returns the unfinished entries in enqueue order
+func (b *Outbox) pending() []*outboxEntry {
+	list := make([]*outboxEntry, 0, len(b.entries))
+	for _, e := range b.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].seq < list[k].seq })
+	return list
+}
+
+// append writes records to the current segment and syncs it, b.mu must be held
+func (b *Outbox) append(records ...*outboxRecord) error {
+	var buf []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	n, err := b.seg.Write(buf)
+	b.segSize += int64(n)
+	if err != nil {
+		return err
+	}
+	if err := b.seg.Sync(); err != nil {
+		return err
+	}
+
+	if b.segSize >= b.rotateAt {
+		// the records are durable already, a failed compaction is retried on the next append
+		_ = b.compact()
+	}
+	return nil
+}
+
+// compact writes the unfinished entries to a new segment and removes the older segments, b.mu must be held
+func (b *Outbox) compact() error {
+	index := b.segIndex + 1
+	f, err := os.OpenFile(b.segmentPath(index), os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	var size int64
+	for _, e := range b.pending() {
+		rec := &outboxRecord{Op: outboxOpPut, OutboxEntry: OutboxEntry{ID: e.id, Cid: e.cid, Payload: e.payload, Attempt: e.attempt, Time: time.Now()}}
+		line, err := json.Marshal(rec)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		n, _ := w.Write(append(line, '\n'))
+		size += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if b.seg != nil {
+		b.seg.Close()
+	}
+	b.seg, b.segIndex, b.segSize = f, index, size
+	b.rotateAt = max(b.opts.SegmentSize, 2*size)
+
+	segments, err := b.segments()
+	if err != nil {
+		return err
+	}
+	for _, i := range segments {
+		if i < index {
+			if err := os.Remove(b.segmentPath(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return syncDir(b.dir)
+}
+
+// segments returns the indexes of the segment files in ascending order
+func (b *Outbox) segments() ([]int, error) {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, outboxSegmentPrefix) || !strings.HasSuffix(name, outboxSegmentSuffix) {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, outboxSegmentPrefix), outboxSegmentSuffix))
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+func (b *Outbox) segmentPath(index int) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%s%08d%s", outboxSegmentPrefix, index, outboxSegmentSuffix))
+}
+
+// appendDeadLetter writes a record to the dead letter file and syncs it, b.mu must be held
+func (b *Outbox) appendDeadLetter(r *outboxRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(b.dir, OUTBOX_DEAD_LETTER), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readDeadLetters reads the dead letter file of the outbox in dir
+func readDeadLetters(dir string) ([]OutboxEntry, error) {
+	f, err := os.Open(filepath.Join(dir, OUTBOX_DEAD_LETTER))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var dead []OutboxEntry
+	seen := make(map[string]int)
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			rec := &outboxRecord{}
+			if json.Unmarshal(line, rec) == nil && rec.ID != "" {
+				if i, ok := seen[rec.ID]; ok {
+					dead[i] = rec.OutboxEntry
+				} else {
+					seen[rec.ID] = len(dead)
+					dead = append(dead, rec.OutboxEntry)
+				}
+			}
+		}
+		if err == io.EOF {
+			return dead, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isPermanentPushError tells whether retrying the push cannot succeed
+func isPermanentPushError(err error) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500 && !isRateLimited(e)
+}
+
+func newOutboxID() (string, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + hex.EncodeToString(b[:]), nil
+}
+
+// syncDir makes renames and removals in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	// some platforms do not support syncing directories
+	_ = d.Sync()
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package jpush
+
+import "os"
+
+// lockFile is a no-op on platforms without flock, only one process may open an outbox directory
+func lockFile(f *os.File) error {
+	return nil
+}
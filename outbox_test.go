@@ -0,0 +1,243 @@
+package jpush
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type outboxRecorder struct {
+	mu    sync.Mutex
+	sent  []string // cids of the sent pushes
+	fail  map[int]error
+	calls int
+}
+
+func (r *outboxRecorder) send(ctx context.Context, data []byte) (*PushResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	if err, ok := r.fail[r.calls]; ok {
+		return nil, err
+	}
+
+	var body struct {
+		Cid string `json:"cid"`
+	}
+	_ = json.Unmarshal(data, &body)
+	r.sent = append(r.sent, body.Cid)
+	return &PushResult{MsgID: MsgID(r.calls)}, nil
+}
+
+func testOutboxPayload() *PayLoad {
+	var pf Platform
+	pf.All()
+	var at Audience
+	at.All()
+	p := NewPayLoad()
+	p.SetPlatform(&pf)
+	p.SetAudience(&at)
+	p.SetMessage(&Message{MsgContent: "hello"})
+	return p
+}
+
+func TestOutboxRetryAndDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	rec := &outboxRecorder{fail: map[int]error{
+		1: errors.New("connection reset"),
+		3: &APIError{StatusCode: http.StatusBadRequest, Code: 1003},
+	}}
+	cids := 0
+	newCid := func() (string, error) {
+		cids++
+		return "cid-" + string(rune('0'+cids)), nil
+	}
+
+	b, err := openOutbox(dir, &OutboxOptions{RetryInterval: time.Millisecond}, rec.send, newCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if _, err := b.Enqueue(testOutboxPayload()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.sent) != 1 || rec.sent[0] != "cid-1" {
+		t.Fatalf("sent = %v, want the retried push with cid-1", rec.sent)
+	}
+
+	if _, err := b.Enqueue(testOutboxPayload()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := b.DeadLetters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Cid != "cid-2" {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+
+	n, err := b.ReplayDeadLetters()
+	if err != nil || n != 1 {
+		t.Fatalf("ReplayDeadLetters = %d, %v", n, err)
+	}
+	if err := b.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.sent) != 2 || rec.sent[1] != "cid-2" {
+		t.Fatalf("sent = %v, want the replayed push to reuse cid-2", rec.sent)
+	}
+	if dead, _ := b.DeadLetters(); len(dead) != 0 {
+		t.Fatalf("dead letters after replay = %+v", dead)
+	}
+}
+
+func TestOutboxReplayOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	newCid := func() (string, error) { return "persisted", nil }
+	blocked := func(ctx context.Context, data []byte) (*PushResult, error) {
+		return nil, errors.New("unavailable")
+	}
+
+	b, err := openOutbox(dir, &OutboxOptions{RetryInterval: time.Hour}, blocked, newCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := b.Enqueue(testOutboxPayload()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		b.mu.Lock()
+		n := len(b.retries)
+		b.mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d entries waiting for a retry, want 3", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &outboxRecorder{}
+	b, err = openOutbox(dir, nil, rec.send, func() (string, error) { return "", errors.New("cid must be reused") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.sent) != 3 {
+		t.Fatalf("sent %d pushes after reopening, want 3", len(rec.sent))
+	}
+	for _, cid := range rec.sent {
+		if cid != "persisted" {
+			t.Fatalf("cid = %q, want the persisted cid", cid)
+		}
+	}
+}
+
+func TestOutboxCompaction(t *testing.T) {
+	dir := t.TempDir()
+	rec := &outboxRecorder{}
+	b, err := openOutbox(dir, &OutboxOptions{SegmentSize: 512}, rec.send, func() (string, error) { return "c", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 20; i++ {
+		if _, err := b.Enqueue(testOutboxPayload()); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	segments, err := filepath.Glob(filepath.Join(dir, outboxSegmentPrefix+"*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("segments = %v, want a single compacted segment", segments)
+	}
+	info, err := os.Stat(segments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= 4*512 {
+		t.Fatalf("segment size = %d, compaction did not drop finished entries", info.Size())
+	}
+}
+
+func TestOutboxLockAndReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	blocked := func(ctx context.Context, data []byte) (*PushResult, error) {
+		return nil, errors.New("unavailable")
+	}
+	newCid := func() (string, error) { return "c1", nil }
+
+	b, err := openOutbox(dir, &OutboxOptions{RetryInterval: time.Hour}, blocked, newCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := b.Enqueue(testOutboxPayload()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := &outboxRecorder{}
+	if _, err := openOutbox(dir, nil, rec.send, newCid); !errors.Is(err, ErrOutboxLocked) {
+		t.Fatalf("err = %v, want ErrOutboxLocked", err)
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, outboxSegmentPrefix+"*"))
+	snapshot, err := ReadOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Pending) != 2 || len(snapshot.DeadLetters) != 0 || rec.calls != 0 {
+		t.Fatalf("snapshot = %+v, %d sends", snapshot, rec.calls)
+	}
+	after, _ := filepath.Glob(filepath.Join(dir, outboxSegmentPrefix+"*"))
+	if len(after) != len(segments) || after[0] != segments[0] {
+		t.Fatalf("segments %v changed to %v by a read-only open", segments, after)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err = openOutbox(dir, nil, rec.send, newCid)
+	if err != nil {
+		t.Fatalf("reopening after Close: %v", err)
+	}
+	b.Close()
+}
@@ -12,11 +12,10 @@ type PayLoad struct {
 	Cid          string        `json:"cid,omitempty"`           // 推送唯一标识符
 }
 
-// NewPayLoad 创建一个新的推送对象
+// NewPayLoad 创建一个新的推送对象，未设置的推送选项在发送时使用客户端的默认推送选项（见 SetDefaultOptions）
 func NewPayLoad() *PayLoad {
 	p := &PayLoad{}
 	p.Options = &Options{}
-	p.Options.ApnsProduction = false
 	return p
 }
 
@@ -1,6 +1,9 @@
 package jpush
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+)
 
 type PlatformType string
 
@@ -98,3 +101,26 @@ func (p *Platform) Remove(os PlatformType) error {
 
 	return errors.New("platform not found")
 }
+
+// MarshalJSON 将平台序列化为 "all" 或平台数组
+func (p *Platform) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Interface())
+}
+
+// UnmarshalJSON 解析 "all" 或平台数组
+func (p *Platform) UnmarshalJSON(data []byte) error {
+	var all string
+	if err := json.Unmarshal(data, &all); err == nil {
+		p.Os = all
+		p.osArray = nil
+		return nil
+	}
+
+	var arr []string
+	if err := json.Unmarshal(data, &arr); err != nil {
+		return err
+	}
+	p.osArray = arr
+	p.Os = arr
+	return nil
+}
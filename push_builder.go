@@ -0,0 +1,314 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const PUSH_MAX_TTL = 10 * 24 * time.Hour // 离线消息最长保留时长
+
+// PushBuilder 链式构建并发送推送，构建过程中的错误会被累积，在 Build、Validate 或 Send 时一并返回
+type PushBuilder struct {
+	client       *JPushClient
+	platform     *Platform
+	audience     *Audience
+	alert        string
+	title        string
+	notification *Notification
+	message      *Message
+	extras       map[string]interface{}
+	options      *Options
+	errs         []error
+}
+
+// NewPush 创建推送构建器
+//
+//	ret, err := client.NewPush().Android().IOS().ToAliases("u1", "u2").Alert("hello").TTL(time.Hour).Send(ctx)
+func (j *JPushClient) NewPush() *PushBuilder {
+	return &PushBuilder{client: j, options: &Options{}}
+}
+
+func (b *PushBuilder) errorf(format string, args ...interface{}) *PushBuilder {
+	b.errs = append(b.errs, fmt.Errorf(format, args...))
+	return b
+}
+
+// AllPlatforms 推送到所有平台
+func (b *PushBuilder) AllPlatforms() *PushBuilder {
+	if b.platform != nil && b.platform.Os != "all" {
+		return b.errorf("platform all cannot be combined with %v", b.platform.Interface())
+	}
+	b.platform = &Platform{}
+	b.platform.All()
+	return b
+}
+
+// Android 添加 Android 平台
+func (b *PushBuilder) Android() *PushBuilder {
+	return b.addPlatform(ANDROID)
+}
+
+// IOS 添加 iOS 平台
+func (b *PushBuilder) IOS() *PushBuilder {
+	return b.addPlatform(IOS)
+}
+
+// WinPhone 添加 Windows Phone 平台
+func (b *PushBuilder) WinPhone() *PushBuilder {
+	return b.addPlatform(WINPHONE)
+}
+
+func (b *PushBuilder) addPlatform(os PlatformType) *PushBuilder {
+	if b.platform == nil {
+		b.platform = &Platform{}
+	}
+	if err := b.platform.Add(os); err != nil {
+		return b.errorf("platform %s: %v", os, err)
+	}
+	return b
+}
+
+// ToAll 推送给所有设备
+func (b *PushBuilder) ToAll() *PushBuilder {
+	if b.audience != nil && b.audience.Object != "all" {
+		return b.errorf("audience all cannot be combined with other targets")
+	}
+	b.audience = &Audience{}
+	b.audience.All()
+	return b
+}
+
+// ToAliases 按别名推送
+func (b *PushBuilder) ToAliases(aliases ...string) *PushBuilder {
+	return b.setTargets(ALIAS, aliases, (*Audience).SetAlias)
+}
+
+// ToRegistrationIDs 按注册 ID 推送
+func (b *PushBuilder) ToRegistrationIDs(ids ...string) *PushBuilder {
+	return b.setTargets(REGISTRATION_ID, ids, (*Audience).SetID)
+}
+
+// ToTags 按标签推送，满足任一标签即可
+func (b *PushBuilder) ToTags(tags ...string) *PushBuilder {
+	return b.setTargets(TAG, tags, (*Audience).SetTag)
+}
+
+// ToTagsAnd 按标签推送，需要满足所有标签
+func (b *PushBuilder) ToTagsAnd(tags ...string) *PushBuilder {
+	return b.setTargets(TAG_AND, tags, (*Audience).SetTagAnd)
+}
+
+// ToTagsNot 排除带有任一标签的设备
+func (b *PushBuilder) ToTagsNot(tags ...string) *PushBuilder {
+	return b.setTargets(TAG_NOT, tags, (*Audience).SetTagNot)
+}
+
+// ToSegments 按用户分群推送
+func (b *PushBuilder) ToSegments(segments ...string) *PushBuilder {
+	return b.setTargets(SEGMENT, segments, (*Audience).SetSegment)
+}
+
+func (b *PushBuilder) setTargets(key AudienceType, targets []string, set func(*Audience, []string)) *PushBuilder {
+	if len(targets) == 0 {
+		return b.errorf("audience %s is empty", key)
+	}
+	if len(targets) > AUDIENCE_MAX_TARGETS {
+		return b.errorf("audience %s has %d targets, more than %d", key, len(targets), AUDIENCE_MAX_TARGETS)
+	}
+	for _, t := range targets {
+		if t == "" {
+			return b.errorf("audience %s contains an empty target", key)
+		}
+	}
+	if b.audience == nil {
+		b.audience = &Audience{}
+	}
+	if b.audience.Object == "all" {
+		return b.errorf("audience %s cannot be combined with audience all", key)
+	}
+	set(b.audience, targets)
+	return b
+}
+
+// Alert 设置通知内容
+func (b *PushBuilder) Alert(alert string) *PushBuilder {
+	b.alert = alert
+	return b
+}
+
+// Title 设置 Android 通知标题
+func (b *PushBuilder) Title(title string) *PushBuilder {
+	b.title = title
+	return b
+}
+
+// Notification 使用完整的通知内容，Alert、Title 与 WithExtras 只补充其中未设置的部分
+func (b *PushBuilder) Notification(n *Notification) *PushBuilder {
+	b.notification = n
+	return b
+}
+
+// Message 设置自定义消息内容
+func (b *PushBuilder) Message(content string) *PushBuilder {
+	if b.message == nil {
+		b.message = &Message{}
+	}
+	b.message.SetContent(content)
+	return b
+}
+
+// WithExtras 添加扩展字段，发送时写入各平台的通知与自定义消息
+func (b *PushBuilder) WithExtras(extras map[string]interface{}) *PushBuilder {
+	if b.extras == nil {
+		b.extras = make(map[string]interface{}, len(extras))
+	}
+	for k, v := range extras {
+		b.extras[k] = v
+	}
+	return b
+}
+
+// TTL 设置离线消息保留时长，精确到秒，最长 10 天
+func (b *PushBuilder) TTL(ttl time.Duration) *PushBuilder {
+	if ttl < time.Second || ttl > PUSH_MAX_TTL {
+		return b.errorf("ttl %v must be between 1s and %v", ttl, PUSH_MAX_TTL)
+	}
+	b.options.SetTimeToLive(int(ttl / time.Second))
+	return b
+}
+
+// SendNo 设置推送序号
+func (b *PushBuilder) SendNo(sendNo int) *PushBuilder {
+	b.options.SetSendNo(sendNo)
+	return b
+}
+
+// ApnsProduction 设置 APNs 是否推送到生产环境，未设置时使用客户端的默认推送选项
+func (b *PushBuilder) ApnsProduction(production bool) *PushBuilder {
+	b.options.SetApnsProduction(production)
+	return b
+}
+
+// ThirdPartyChannel 设置厂商通道参数
+func (b *PushBuilder) ThirdPartyChannel(channel ThirdChannelType, opts ThirdPartyOptions) *PushBuilder {
+	b.options.AddThirdPartyChannel(channel, opts)
+	return b
+}
+
+// Validate 返回构建过程中累积的错误以及推送缺少的必填内容
+func (b *PushBuilder) Validate() error {
+	errs := append([]error(nil), b.errs...)
+	if b.platform == nil || b.platform.Os == nil {
+		errs = append(errs, errors.New("platform is required"))
+	}
+	if b.audience == nil || b.audience.Object == nil {
+		errs = append(errs, errors.New("audience is required"))
+	}
+	if b.alert == "" && b.notification == nil && b.message == nil {
+		errs = append(errs, errors.New("alert, notification or message is required"))
+	}
+	if b.message != nil && b.message.MsgContent == "" {
+		errs = append(errs, errors.New("message content is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// Build 校验并返回推送对象
+func (b *PushBuilder) Build() (*PayLoad, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := NewPayLoad()
+	p.SetPlatform(b.platform)
+	p.SetAudience(b.audience)
+	p.SetOptions(b.options)
+
+	if b.message != nil {
+		m := *b.message
+		m.Extras = withExtras(m.Extras, b.extras)
+		p.SetMessage(&m)
+	}
+
+	if b.alert != "" || b.notification != nil {
+		n := &Notification{}
+		if b.notification != nil {
+			c := *b.notification
+			n = &c
+		}
+		if n.Alert == "" {
+			n.SetAlert(b.alert)
+		}
+		if b.title != "" || len(b.extras) > 0 {
+			if b.hasPlatform(ANDROID) {
+				a := &AndroidNotification{Alert: n.Alert}
+				if n.Android != nil {
+					c := *n.Android
+					a = &c
+				}
+				if a.Title == nil && b.title != "" {
+					a.Title = b.title
+				}
+				a.Extras = withExtras(a.Extras, b.extras)
+				n.SetAndroid(a)
+			}
+			if b.hasPlatform(IOS) && len(b.extras) > 0 {
+				i := &IosNotification{Alert: n.Alert}
+				if n.Ios != nil {
+					c := *n.Ios
+					i = &c
+				}
+				i.Extras = withExtras(i.Extras, b.extras)
+				n.SetIos(i)
+			}
+		}
+		p.SetNotification(n)
+	}
+	return p, nil
+}
+
+// Send 校验并发送推送，与 Push 一样经过去重与合并，返回推送结果，JPush 返回的错误为 *APIError
+func (b *PushBuilder) Send(ctx context.Context) (*PushResult, error) {
+	p, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return b.client.pushFrontResult(ctx, data)
+}
+
+func (b *PushBuilder) hasPlatform(os PlatformType) bool {
+	if b.platform.Os == "all" {
+		return true
+	}
+	for _, v := range b.platform.osArray {
+		if v == string(os) {
+			return true
+		}
+	}
+	return false
+}
+
+// withExtras returns extras with the builder extras added, keys already in extras win
+func withExtras(extras, add map[string]interface{}) map[string]interface{} {
+	if len(add) == 0 {
+		return extras
+	}
+
+	merged := make(map[string]interface{}, len(extras)+len(add))
+	for k, v := range add {
+		merged[k] = v
+	}
+	for k, v := range extras {
+		merged[k] = v
+	}
+	return merged
+}
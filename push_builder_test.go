@@ -0,0 +1,80 @@
+package jpush
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushBuilder(t *testing.T) {
+	var body []byte
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"sendno":"0","msg_id":"42"}`, func(req *http.Request) {
+		body, _ = io.ReadAll(req.Body)
+	}))
+
+	ret, err := c.NewPush().Android().IOS().
+		ToAliases("u1", "u2").
+		Alert("hello").
+		WithExtras(map[string]interface{}{"order": "1001"}).
+		TTL(time.Hour).
+		Send(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.MsgID != 42 {
+		t.Fatalf("msg id = %d, want 42", ret.MsgID)
+	}
+
+	var sent struct {
+		Platform     []string            `json:"platform"`
+		Audience     map[string][]string `json:"audience"`
+		Notification Notification        `json:"notification"`
+		Options      Options             `json:"options"`
+	}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		t.Fatal(err)
+	}
+	n := sent.Notification
+	if strings.Join(sent.Platform, ",") != "android,ios" || len(sent.Audience["alias"]) != 2 || n.Alert != "hello" ||
+		n.Android == nil || n.Android.Extras["order"] != "1001" || n.Ios == nil || n.Ios.Extras["order"] != "1001" ||
+		sent.Options.TimeToLive != 3600 {
+		t.Fatalf("sent = %s", body)
+	}
+
+	// errors are accumulated and nothing is sent
+	body = nil
+	_, err = c.NewPush().Android().AllPlatforms().ToAliases().TTL(-time.Second).Send(context.Background())
+	if err == nil || body != nil {
+		t.Fatal("an invalid push should not be sent")
+	}
+	for _, want := range []string{"platform all", "audience alias is empty", "ttl", "audience is required", "alert, notification or message"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("err = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestPushBuilderUsesDedup(t *testing.T) {
+	sent := 0
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(stubTransport(http.StatusOK, `{"sendno":"0","msg_id":"42"}`, func(*http.Request) { sent++ }))
+	c.EnableDedup(nil)
+
+	send := func() (*PushResult, error) {
+		return c.NewPush().Android().ToAliases("u1").Alert("once").Send(context.Background())
+	}
+	if ret, err := send(); err != nil || ret.MsgID != 42 {
+		t.Fatalf("first push = %v, %v", ret, err)
+	}
+	if _, err := send(); err != ErrDuplicatePush {
+		t.Fatalf("err = %v, want ErrDuplicatePush", err)
+	}
+	if sent != 1 {
+		t.Fatalf("sent %d pushes, want 1", sent)
+	}
+}
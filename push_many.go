@@ -0,0 +1,150 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	AUDIENCE_MAX_TARGETS = 1000 // 单次推送最多支持的注册 ID / 别名数量
+
+	pushRateLimitRetries = 3 // times a chunk rejected by the rate limit is retried
+)
+
+// PushToManyOptions 批量推送参数，零值字段使用默认值
+type PushToManyOptions struct {
+	Concurrency int // 并发请求数，默认 4
+	ChunkSize   int // 每次推送的目标数量，默认且最大为 1000
+}
+
+// PushChunkResult 单个分片的推送结果
+type PushChunkResult struct {
+	ResponseTiming
+	Targets []string // 分片中的推送目标
+	MsgID   MsgID    // 消息 ID，推送失败时为 0
+	Error   error    // 推送失败的原因，JPush 返回的错误为 *APIError
+}
+
+// PushToManyResult 批量推送的汇总结果
+type PushToManyResult struct {
+	Chunks []PushChunkResult // 按推送目标顺序排列的分片结果
+}
+
+// MsgIDs 返回所有推送成功分片的消息 ID
+func (r *PushToManyResult) MsgIDs() []MsgID {
+	var ids []MsgID
+	for _, c := range r.Chunks {
+		if c.Error == nil {
+			ids = append(ids, c.MsgID)
+		}
+	}
+	return ids
+}
+
+// Failed 返回推送失败的分片
+func (r *PushToManyResult) Failed() []PushChunkResult {
+	var failed []PushChunkResult
+	for _, c := range r.Chunks {
+		if c.Error != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// PushToMany 将推送目标按 1000 个一组拆分后以有限的并发推送，template 中的 Audience 与 Cid 会被替换。
+// audienceType 仅支持 REGISTRATION_ID 与 ALIAS。遇到频率限制时会等待限制重置后重试该分片。
+// 单个分片失败不会中断其它分片，失败信息见返回结果的 Failed。
+func (j *JPushClient) PushToMany(ctx context.Context, template *PayLoad, audienceType AudienceType, targets []string, opts *PushToManyOptions) (*PushToManyResult, error) {
+	if template == nil || template.Platform == nil {
+		return nil, errors.New("payload template and its platform must be set")
+	}
+	if audienceType != REGISTRATION_ID && audienceType != ALIAS {
+		return nil, errors.New("audience type must be registration_id or alias")
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("targets is empty")
+	}
+
+	o := PushToManyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.ChunkSize <= 0 || o.ChunkSize > AUDIENCE_MAX_TARGETS {
+		o.ChunkSize = AUDIENCE_MAX_TARGETS
+	}
+
+	chunks := chunkStrings(targets, o.ChunkSize)
+	ret := &PushToManyResult{Chunks: make([]PushChunkResult, len(chunks))}
+
+	sem := make(chan struct{}, o.Concurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		ret.Chunks[i].Targets = chunk
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			ret.Chunks[i].Error = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(c *PushChunkResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ret, err := j.pushChunk(ctx, template, audienceType, c.Targets)
+			if err != nil {
+				c.Error = err
+				return
+			}
+			c.MsgID, c.Timing = ret.MsgID, ret.Timing
+		}(&ret.Chunks[i])
+	}
+	wg.Wait()
+
+	return ret, nil
+}
+
+// pushChunk pushes template to one chunk of targets, retrying when rejected by the rate limit
+func (j *JPushClient) pushChunk(ctx context.Context, template *PayLoad, audienceType AudienceType, targets []string) (*PushResult, error) {
+	var audience Audience
+	audience.set(audienceType, targets)
+
+	p := *template
+	p.Audience = &audience
+	p.Cid = ""
+
+	data, err := p.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		ret, err := j.pushResult(ctx, data)
+		if err == nil {
+			return ret, nil
+		}
+		if !isRateLimited(err) || attempt >= pushRateLimitRetries {
+			return nil, err
+		}
+	}
+}
+
+// chunkStrings splits s into chunks of at most size items
+func chunkStrings(s []string, size int) [][]string {
+	var chunks [][]string
+	for start := 0; start < len(s); start += size {
+		end := start + size
+		if end > len(s) {
+			end = len(s)
+		}
+		chunks = append(chunks, s[start:end])
+	}
+	return chunks
+}
@@ -0,0 +1,44 @@
+package jpush
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestChunkStrings(t *testing.T) {
+	targets := make([]string, 2500)
+	chunks := chunkStrings(targets, AUDIENCE_MAX_TARGETS)
+	if len(chunks) != 3 || len(chunks[0]) != 1000 || len(chunks[2]) != 500 {
+		t.Errorf("unexpected chunks %d", len(chunks))
+	}
+}
+
+func TestRateGate(t *testing.T) {
+	var g rateGate
+
+	h := http.Header{}
+	h.Set(HEADER_RATE_LIMIT_LIMIT, "600")
+	h.Set(HEADER_RATE_LIMIT_REMAINING, "599")
+	h.Set(HEADER_RATE_LIMIT_RESET, "60")
+	g.update(&apiResponse{StatusCode: http.StatusOK, Header: h})
+	if err := g.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := g.state(); s.Limit != 600 || s.Remaining != 599 || s.Reset != time.Minute {
+		t.Errorf("unexpected state %+v", s)
+	}
+
+	h.Set(HEADER_RATE_LIMIT_REMAINING, "0")
+	g.update(&apiResponse{StatusCode: http.StatusOK, Header: h})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected gate to block until reset, got %v", err)
+	}
+
+	if !isRateLimited(parseAPIError(http.StatusTooManyRequests, []byte(`{"error":{"code":2002,"message":"rate limit"}}`))) {
+		t.Error("expected rate limited error")
+	}
+}
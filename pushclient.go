@@ -1,16 +1,31 @@
 package jpush
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type JPushClient struct {
 	AppKey       string // app key
 	MasterSecret string // master secret
+
+	cidPool     *CidPool
+	coalescer   *Coalescer
+	dedup       *deduper
+	lanes       *laneScheduler
+	breakers    breakerGroup
+	failover    *failover
+	middlewares []Middleware
+	dryRun      *DryRunLog
+	pushRate    rateGate
+	credentials CredentialsProvider
+	transport   http.RoundTripper
+	defaults    map[string]interface{}
 }
 
 const (
@@ -23,6 +38,13 @@ const (
 	HOST_IMAGES   = "https://api.jpush.cn/v3/images"
 )
 
+// PushResult 推送成功的返回结果
+type PushResult struct {
+	ResponseTiming
+	SendNo string `json:"sendno"` // 推送序号
+	MsgID  MsgID  `json:"msg_id"` // 消息 ID
+}
+
 // NewJPushClient returns a new JPushClient
 func NewJPushClient(appKey string, masterSecret string) *JPushClient {
 	return &JPushClient{AppKey: appKey, MasterSecret: masterSecret}
@@ -30,18 +52,43 @@ func NewJPushClient(appKey string, masterSecret string) *JPushClient {
 
 // GetAuthorization returns the authorization string
 func (j *JPushClient) GetAuthorization() string {
-	return j.AppKey + ":" + j.MasterSecret
+	c, _ := j.Credentials()
+	return c.AppKey + ":" + c.MasterSecret
+}
+
+// SetTransport 设置客户端所有请求使用的 Transport，用于在多个客户端之间复用连接池，
+// 未设置时使用包内共用的连接池。Transport 不会被修改，超时通过请求的 context 控制。
+func (j *JPushClient) SetTransport(transport http.RoundTripper) {
+	j.transport = transport
+}
+
+// newRequest returns a request carrying the common JPush headers and the app basic auth
+func (j *JPushClient) newRequest(method, url string) *HttpRequest {
+	c, err := j.Credentials()
+	req := newAuthRequest(method, url, c.AppKey, c.MasterSecret)
+	if err != nil {
+		req.err = fmt.Errorf("jpush credentials: %w", err)
+	}
+	req.middlewares = j.middlewares
+	if j.transport != nil {
+		req.SetTransport(j.transport)
+	}
+	if j.dryRun != nil {
+		req.wrapTransport(j.dryRun.wrap)
+	}
+	if j.failover != nil {
+		req.wrapTransport(j.failover.wrap)
+	}
+	// the breaker sees the outcome after failover
+	if j.breakers != nil {
+		req.wrapTransport(j.breakers.wrap)
+	}
+	return req
 }
 
 // GetCid returns the cid list as byte array
 func (j *JPushClient) GetCid(count int, push_type string) ([]byte, error) {
-	req := Get(HOST_CID)
-	req.SetTimeout(DEFAULT_CONNECT_TIMEOUT*time.Second, DEFAULT_READ_WRITE_TIMEOUT*time.Second)
-	req.SetHeader("Connection", "Keep-Alive")
-	req.SetHeader("Charset", CHARSET)
-	req.SetBasicAuth(j.AppKey, j.MasterSecret)
-	req.SetHeader("Content-Type", CONTENT_TYPE_JSON)
-	req.SetProtocolVersion("HTTP/1.1")
+	req := j.newRequest(http.MethodGet, HOST_CID)
 	req.SetQueryParam("count", strconv.Itoa(count))
 	req.SetQueryParam("type", push_type)
 
@@ -53,7 +100,7 @@ func (j *JPushClient) SendSms(data []byte) (string, error) {
 	return j.sendSmsBytes(data)
 }
 func (j *JPushClient) sendSmsBytes(content []byte) (string, error) {
-	ret, err := SendPostBytes2(SMS, content, j.AppKey, j.MasterSecret)
+	ret, err := j.newRequest(http.MethodPost, SMS).SetBody(content).String()
 	if err != nil {
 		return "", err
 	}
@@ -67,11 +114,92 @@ func (j *JPushClient) sendSmsBytes(content []byte) (string, error) {
 
 // Push 推送消息
 func (j *JPushClient) Push(data []byte) (string, error) {
-	return j.sendPushBytes(data)
+	return j.pushFront(context.Background(), data)
+}
+
+// pushFront passes a push through the optional dedup and coalescing layers in front of Push
+func (j *JPushClient) pushFront(ctx context.Context, data []byte) (string, error) {
+	if j.dedup != nil {
+		return j.dedup.push(ctx, data)
+	}
+	return j.pushCoalesced(ctx, data)
+}
+
+// pushCoalesced sends a push through the coalescer when coalescing is enabled
+func (j *JPushClient) pushCoalesced(ctx context.Context, data []byte) (string, error) {
+	if j.coalescer != nil {
+		return j.coalescer.push(ctx, data)
+	}
+	return j.push(ctx, data)
+}
+
+// push sends a push request and returns the response body as string
+func (j *JPushClient) push(ctx context.Context, data []byte) (string, error) {
+	data, err := j.withCid(data, CID_TYPE_PUSH)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := j.sendPushBytes(ctx, data)
+	if err != nil {
+		return "", err
+	}
+
+	ret := string(resp.Body)
+	if strings.Contains(ret, SUCCESS_FLAG) {
+		return ret, nil
+	}
+
+	return "", errors.New(ret)
+}
+
+// pushResult sends a push request and returns the typed result, failures are returned as *APIError
+func (j *JPushClient) pushResult(ctx context.Context, data []byte) (*PushResult, error) {
+	data, err := j.withCid(data, CID_TYPE_PUSH)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := j.sendPushBytes(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &PushResult{}
+	if err := resp.decode(ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// pushFrontResult is pushFront returning the typed result. With dedup or coalescing
+// enabled the result is decoded from the shared response body and carries no timing.
+func (j *JPushClient) pushFrontResult(ctx context.Context, data []byte) (*PushResult, error) {
+	if j.dedup == nil && j.coalescer == nil {
+		return j.pushResult(ctx, data)
+	}
+
+	body, err := j.pushFront(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	ret := &PushResult{}
+	if err := json.Unmarshal([]byte(body), ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
 }
 
 // CreateSchedule 创建推送计划
 func (j *JPushClient) CreateSchedule(data []byte) (string, error) {
+	data, err := j.withCid(data, CID_TYPE_SCHEDULE)
+	if err != nil {
+		return "", err
+	}
+	data, err = j.withDefaults(data, "push")
+	if err != nil {
+		return "", err
+	}
 	return j.sendScheduleBytes(data)
 }
 
@@ -87,7 +215,7 @@ func (j *JPushClient) GetSchedule(id string) (string, error) {
 
 // SendPushString sends a push request and returns the response body as string
 func (j *JPushClient) sendPushString(content string) (string, error) {
-	ret, err := SendPostString(HOST_PUSH, content, j.AppKey, j.MasterSecret)
+	ret, err := j.newRequest(http.MethodPost, HOST_PUSH).SetBody(content).String()
 	if err != nil {
 		return "", err
 	}
@@ -99,23 +227,44 @@ func (j *JPushClient) sendPushString(content string) (string, error) {
 	return "", errors.New(ret)
 }
 
-// SendPushBytes sends a push request and returns the response body as string
-func (j *JPushClient) sendPushBytes(content []byte) (string, error) {
-	ret, err := SendPostBytes2(HOST_PUSH, content, j.AppKey, j.MasterSecret)
+// SendPushBytes sends a push request once its priority lane and the push rate limit allow it and returns the raw response
+func (j *JPushClient) sendPushBytes(ctx context.Context, content []byte) (*apiResponse, error) {
+	content, err := j.withDefaults(content)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if strings.Contains(ret, SUCCESS_FLAG) {
-		return ret, nil
+	if j.lanes != nil {
+		l, err := j.lanes.acquire(ctx, laneFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		defer j.lanes.release(l)
 	}
 
-	return "", errors.New(ret)
+	if err := j.pushRate.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req := j.newRequest(http.MethodPost, HOST_PUSH)
+	req.SetContext(ctx)
+	req.SetBody(content)
+
+	resp, err := execute(req)
+	if err != nil {
+		return nil, err
+	}
+
+	j.pushRate.update(resp)
+	if j.lanes != nil {
+		j.lanes.update(resp)
+	}
+	return resp, nil
 }
 
 // SendScheduleBytes sends a schedule request and returns the response body as string
 func (j *JPushClient) sendScheduleBytes(content []byte) (string, error) {
-	ret, err := SendPostBytes2(HOST_SCHEDULE, content, j.AppKey, j.MasterSecret)
+	ret, err := j.newRequest(http.MethodPost, HOST_SCHEDULE).SetBody(content).String()
 	if err != nil {
 		return "", err
 	}
@@ -129,13 +278,7 @@ func (j *JPushClient) sendScheduleBytes(content []byte) (string, error) {
 
 // SendGetScheduleRequest sends a get schedule request and returns the response body as string
 func (j *JPushClient) sendGetScheduleRequest(schedule_id string) (string, error) {
-	req := Get(HOST_SCHEDULE)
-	req.SetTimeout(DEFAULT_CONNECT_TIMEOUT*time.Second, DEFAULT_READ_WRITE_TIMEOUT*time.Second)
-	req.SetHeader("Connection", "Keep-Alive")
-	req.SetHeader("Charset", CHARSET)
-	req.SetBasicAuth(j.AppKey, j.MasterSecret)
-	req.SetHeader("Content-Type", CONTENT_TYPE_JSON)
-	req.SetProtocolVersion("HTTP/1.1")
+	req := j.newRequest(http.MethodGet, HOST_SCHEDULE)
 	req.SetQueryParam("schedule_id", schedule_id)
 
 	return req.String()
@@ -143,13 +286,7 @@ func (j *JPushClient) sendGetScheduleRequest(schedule_id string) (string, error)
 
 // SendDeleteScheduleRequest sends a delete schedule request and returns the response body as string
 func (j *JPushClient) sendDeleteScheduleRequest(schedule_id string) (string, error) {
-	req := Delete(HOST_SCHEDULE)
-	req.SetTimeout(DEFAULT_CONNECT_TIMEOUT*time.Second, DEFAULT_READ_WRITE_TIMEOUT*time.Second)
-	req.SetHeader("Connection", "Keep-Alive")
-	req.SetHeader("Charset", CHARSET)
-	req.SetBasicAuth(j.AppKey, j.MasterSecret)
-	req.SetHeader("Content-Type", CONTENT_TYPE_JSON)
-	req.SetProtocolVersion("HTTP/1.1")
+	req := j.newRequest(http.MethodDelete, HOST_SCHEDULE)
 	req.SetQueryParam("schedule_id", schedule_id)
 
 	return req.String()
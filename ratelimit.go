@@ -0,0 +1,109 @@
+package jpush
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JPush 在响应头中返回当前 API 的频率限制
+const (
+	HEADER_RATE_LIMIT_LIMIT     = "X-Rate-Limit-Limit"
+	HEADER_RATE_LIMIT_REMAINING = "X-Rate-Limit-Remaining"
+	HEADER_RATE_LIMIT_RESET     = "X-Rate-Limit-Reset"
+
+	ERR_CODE_RATE_LIMIT = 2002 // 请求频率超出限制
+)
+
+// RateLimit 频率限制状态
+type RateLimit struct {
+	Limit     int           // 时间窗口内允许的请求数
+	Remaining int           // 时间窗口内剩余的请求数
+	Reset     time.Duration // 距离时间窗口重置的时长
+}
+
+// parseRateLimit reads the rate limit headers, it returns nil when they are absent
+func parseRateLimit(h http.Header) *RateLimit {
+	if h == nil || h.Get(HEADER_RATE_LIMIT_REMAINING) == "" {
+		return nil
+	}
+
+	rl := &RateLimit{}
+	rl.Limit, _ = strconv.Atoi(h.Get(HEADER_RATE_LIMIT_LIMIT))
+	rl.Remaining, _ = strconv.Atoi(h.Get(HEADER_RATE_LIMIT_REMAINING))
+	reset, _ := strconv.Atoi(h.Get(HEADER_RATE_LIMIT_RESET))
+	rl.Reset = time.Duration(reset) * time.Second
+	return rl
+}
+
+// isRateLimited tells whether the response was rejected by the JPush rate limit
+func isRateLimited(err error) bool {
+	e, ok := err.(*APIError)
+	return ok && (e.StatusCode == http.StatusTooManyRequests || e.Code == ERR_CODE_RATE_LIMIT)
+}
+
+// rateGate holds requests back once JPush reports that the rate limit quota is exhausted.
+// The zero value is ready to use.
+type rateGate struct {
+	mu    sync.Mutex
+	until time.Time
+	last  RateLimit
+}
+
+// wait blocks until the quota window has been reset or ctx is done
+func (g *rateGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	d := time.Until(g.until)
+	g.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// update records the rate limit state of a response
+func (g *rateGate) update(resp *apiResponse) {
+	rl := parseRateLimit(resp.Header)
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	if rl == nil && !limited {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	reset := time.Second
+	if rl != nil {
+		g.last = *rl
+		if rl.Reset > 0 {
+			reset = rl.Reset
+		}
+	}
+	if limited || (rl != nil && rl.Remaining <= 0) {
+		g.until = time.Now().Add(reset)
+	}
+}
+
+// state returns the last seen rate limit
+func (g *rateGate) state() RateLimit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.last
+}
+
+// PushRateLimit 返回最近一次推送响应中的频率限制状态
+func (j *JPushClient) PushRateLimit() RateLimit {
+	return j.pushRate.state()
+}
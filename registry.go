@@ -0,0 +1,338 @@
+package jpush
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrUnknownApp = errors.New("jpush: unknown app")
+
+// RegistryOptions 多应用客户端注册表参数
+type RegistryOptions struct {
+	MaxIdleConnsPerHost  int               // 每个域名保持的空闲连接数，默认 16
+	MaxConcurrency       int               // 所有应用同时进行中的请求数上限（直到响应体读完），0 表示不限制
+	MaxRequestsPerSecond int               // 所有应用每秒最多发出的请求数，0 表示不限制
+	Transport            http.RoundTripper // 可选，所有应用共用的底层 Transport，默认创建一个带连接池的 http.Transport
+}
+
+// AppConfig 注册表配置文件中的一个应用
+type AppConfig struct {
+	Name            string `json:"name"`                       // 应用名称
+	AppKey          string `json:"app_key"`                    // app key
+	MasterSecret    string `json:"master_secret,omitempty"`    // master secret
+	CredentialsFile string `json:"credentials_file,omitempty"` // 可选，从文件读取并自动重新加载鉴权信息，优先于 app_key 与 master_secret
+	Environment     string `json:"environment,omitempty"`      // 可选，推送环境，使用该环境预设的默认推送选项
+}
+
+// RegistryConfig 注册表配置文件
+type RegistryConfig struct {
+	MaxIdleConnsPerHost  int         `json:"max_idle_conns_per_host,omitempty"` // 每个域名保持的空闲连接数
+	MaxConcurrency       int         `json:"max_concurrency,omitempty"`         // 所有应用同时进行中的请求数上限
+	MaxRequestsPerSecond int         `json:"max_requests_per_second,omitempty"` // 所有应用每秒最多发出的请求数
+	Apps                 []AppConfig `json:"apps"`                              // 应用列表
+}
+
+// Registry 多应用客户端注册表，按应用名称或 app key 查找客户端。
+// 所有客户端共用同一个连接池以及全局的并发数与请求速率限制；JPush 返回的频率限制配额按应用计算，
+// 由各应用的客户端分别跟踪，可以通过 RateLimits 查看。
+type Registry struct {
+	transport http.RoundTripper
+	base      http.RoundTripper
+
+	mu      sync.RWMutex
+	byName  map[string]*JPushClient
+	byKey   map[string]*JPushClient
+	appKeys map[string]string // name -> app key
+}
+
+// NewRegistry 创建多应用客户端注册表
+func NewRegistry(opts *RegistryOptions) *Registry {
+	o := RegistryOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxIdleConnsPerHost <= 0 {
+		o.MaxIdleConnsPerHost = DEFAULT_MAX_IDLE_CONNS_PER_HOST
+	}
+
+	base := o.Transport
+	if base == nil {
+		base = newPooledTransport(o.MaxIdleConnsPerHost)
+	}
+
+	return &Registry{
+		transport: limitRequests(base, o.MaxConcurrency, o.MaxRequestsPerSecond),
+		base:      base,
+		byName:    make(map[string]*JPushClient),
+		byKey:     make(map[string]*JPushClient),
+		appKeys:   make(map[string]string),
+	}
+}
+
+// LoadRegistry 从 JSON 配置文件创建多应用客户端注册表
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg RegistryConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid registry config %s: %v", path, err)
+	}
+
+	r := NewRegistry(&RegistryOptions{
+		MaxIdleConnsPerHost:  cfg.MaxIdleConnsPerHost,
+		MaxConcurrency:       cfg.MaxConcurrency,
+		MaxRequestsPerSecond: cfg.MaxRequestsPerSecond,
+	})
+	for _, app := range cfg.Apps {
+		var provider CredentialsProvider = StaticCredentials{AppKey: app.AppKey, MasterSecret: app.MasterSecret}
+		if app.CredentialsFile != "" {
+			f, err := NewFileCredentials(app.CredentialsFile, 0)
+			if err != nil {
+				return nil, fmt.Errorf("app %s: %v", app.Name, err)
+			}
+			provider = f
+		}
+		c, err := r.Add(app.Name, provider)
+		if err != nil {
+			return nil, err
+		}
+		if app.Environment != "" {
+			if err := c.SetEnvironment(Environment(app.Environment), nil); err != nil {
+				return nil, fmt.Errorf("app %s: %v", app.Name, err)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Add 注册一个应用并返回它的客户端，返回的客户端可以继续启用 CID 池、熔断等功能
+func (r *Registry) Add(name string, provider CredentialsProvider) (*JPushClient, error) {
+	c := NewJPushClientWithCredentials(provider)
+	if err := r.Register(name, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Register 注册一个已创建的客户端，客户端的请求改为使用注册表共用的连接池
+func (r *Registry) Register(name string, c *JPushClient) error {
+	if name == "" {
+		return errors.New("app name is required")
+	}
+	creds, err := c.Credentials()
+	if err != nil {
+		return fmt.Errorf("app %s: %v", name, err)
+	}
+	if creds.AppKey == "" {
+		return fmt.Errorf("app %s: app key is empty", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byName[name]; ok {
+		return fmt.Errorf("app %s is already registered", name)
+	}
+	if _, ok := r.byKey[creds.AppKey]; ok {
+		return fmt.Errorf("app key %s is already registered", creds.AppKey)
+	}
+
+	c.SetTransport(r.transport)
+	r.byName[name] = c
+	r.byKey[creds.AppKey] = c
+	r.appKeys[name] = creds.AppKey
+	return nil
+}
+
+// Remove 移除应用
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.byName, name)
+	delete(r.byKey, r.appKeys[name])
+	delete(r.appKeys, name)
+}
+
+// Client 按应用名称或 app key 返回客户端，未注册时返回 ErrUnknownApp
+func (r *Registry) Client(app string) (*JPushClient, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if c, ok := r.byName[app]; ok {
+		return c, nil
+	}
+	if c, ok := r.byKey[app]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownApp, app)
+}
+
+// Apps 返回已注册的应用名称
+func (r *Registry) Apps() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Push 使用指定应用推送
+func (r *Registry) Push(app string, data []byte) (string, error) {
+	c, err := r.Client(app)
+	if err != nil {
+		return "", err
+	}
+	return c.Push(data)
+}
+
+// SendSms 使用指定应用发送短信
+func (r *Registry) SendSms(app string, data []byte) (string, error) {
+	c, err := r.Client(app)
+	if err != nil {
+		return "", err
+	}
+	return c.SendSms(data)
+}
+
+// GetReport 获取指定应用的消息统计
+func (r *Registry) GetReport(app string, msgIDs string) (string, error) {
+	c, err := r.Client(app)
+	if err != nil {
+		return "", err
+	}
+	return c.GetReport(msgIDs)
+}
+
+// GetReceivedDetail 获取指定应用的消息送达统计
+func (r *Registry) GetReceivedDetail(app string, msgIDs []int64) (*ReceivedDetails, error) {
+	c, err := r.Client(app)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetReceivedDetail(msgIDs)
+}
+
+// RateLimits 返回各应用最近一次推送响应中的频率限制状态
+func (r *Registry) RateLimits() map[string]RateLimit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	limits := make(map[string]RateLimit, len(r.byName))
+	for name, c := range r.byName {
+		limits[name] = c.PushRateLimit()
+	}
+	return limits
+}
+
+// CloseIdleConnections 关闭共用连接池中的空闲连接
+func (r *Registry) CloseIdleConnections() {
+	if t, ok := r.base.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+// limitRequests wraps base so that at most concurrency requests are in flight until their
+// response body is closed, and at most perSecond requests start each second. The returned
+// transport is never an *http.Transport, so requests do not modify the shared base.
+func limitRequests(base http.RoundTripper, concurrency, perSecond int) http.RoundTripper {
+	var sem chan struct{}
+	if concurrency > 0 {
+		sem = make(chan struct{}, concurrency)
+	}
+	var pace *requestPacer
+	if perSecond > 0 {
+		pace = &requestPacer{interval: time.Second / time.Duration(perSecond)}
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		release := func() {
+			if sem != nil {
+				<-sem
+			}
+		}
+
+		if pace != nil {
+			if err := pace.wait(ctx); err != nil {
+				release()
+				return nil, err
+			}
+		}
+
+		resp, err := base.RoundTrip(req)
+		if err != nil || resp.Body == nil {
+			release()
+			return resp, err
+		}
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	})
+}
+
+// releaseBody frees the concurrency slot of a request once its response body is closed
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// requestPacer spaces requests out evenly
+type requestPacer struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request slot or until ctx is done
+func (p *requestPacer) wait(ctx context.Context) error {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(p.interval)
+	p.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
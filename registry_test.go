@@ -0,0 +1,116 @@
+package jpush
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		user, _, _ := req.BasicAuth()
+		mu.Lock()
+		seen = append(seen, user+" "+req.URL.Host+req.URL.Path)
+		mu.Unlock()
+		return stubResponse(req, http.StatusOK, `{"sendno":"0","msg_id":"1"}`), nil
+	})
+
+	file := filepath.Join(t.TempDir(), "apps.json")
+	config := `{"apps":[{"name":"shop","app_key":"k1","master_secret":"s1"},{"name":"news","app_key":"k2","master_secret":"s2"}]}`
+	if err := os.WriteFile(file, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRegistry(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apps := loaded.Apps(); strings.Join(apps, ",") != "news,shop" {
+		t.Fatalf("apps = %v", apps)
+	}
+
+	r := NewRegistry(&RegistryOptions{Transport: transport, MaxConcurrency: 2})
+	for _, app := range []AppConfig{{Name: "shop", AppKey: "k1", MasterSecret: "s1"}, {Name: "news", AppKey: "k2", MasterSecret: "s2"}} {
+		if _, err := r.Add(app.Name, StaticCredentials{AppKey: app.AppKey, MasterSecret: app.MasterSecret}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Add("other", StaticCredentials{AppKey: "k1", MasterSecret: "s3"}); err == nil {
+		t.Fatal("an app key can only be registered once")
+	}
+
+	payload := `{"platform":"all","audience":"all","notification":{"alert":"hi"}}`
+	if _, err := r.Push("shop", []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Push("k2", []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SendSms("news", []byte(`{"mobile":"13800000000","temp_id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Push("nobody", []byte(payload)); !errors.Is(err, ErrUnknownApp) {
+		t.Fatalf("err = %v, want ErrUnknownApp", err)
+	}
+
+	want := "k1 api.jpush.cn/v3/push,k2 api.jpush.cn/v3/push,k2 api.sms.jpush.cn/v1/messages"
+	if got := strings.Join(seen, ","); got != want {
+		t.Fatalf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestLimitRequestsHoldsSlotUntilBodyClosed(t *testing.T) {
+	base := stubTransport(http.StatusOK, "{}", nil)
+	rt := limitRequests(base, 1, 0)
+
+	first, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "https://api.jpush.cn/v3/push/cid", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "https://api.jpush.cn/v3/push/cid", nil).WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, the slot should be held until the first body is closed", err)
+	}
+
+	first.Body.Close()
+	if _, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "https://api.jpush.cn/v3/push/cid", nil)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetTransportDoesNotModifyTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"cidlist":["c1"]}`))
+	}))
+	defer ts.Close()
+
+	transport := &http.Transport{}
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(transport)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.newRequest(http.MethodGet, ts.URL+"/v3/push/cid").Bytes(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if transport.DialContext != nil || transport.Proxy != nil {
+		t.Fatal("the caller's transport was modified")
+	}
+}
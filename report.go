@@ -1,21 +1,362 @@
 package jpush
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HOST_REPORT_RECEIVED_DETAIL = "https://report.jpush.cn/v3/received/detail"
+	HOST_REPORT_STATUS_MESSAGE  = "https://report.jpush.cn/v3/status/message"
+	HOST_REPORT_MESSAGES_DETAIL = "https://report.jpush.cn/v3/messages/detail"
+	HOST_REPORT_USERS           = "https://report.jpush.cn/v3/users"
+
+	REPORT_MAX_MSG_IDS = 100 // 单次报表请求最多支持的 msg_id 数量
+)
+
+// MsgID 推送消息 ID，兼容 JSON 中的数字与字符串两种格式
+type MsgID int64
+
+func (m MsgID) String() string {
+	return strconv.FormatInt(int64(m), 10)
+}
+
+func (m *MsgID) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*m = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*m = MsgID(v)
+	return nil
+}
+
+type TimeUnit string
+
+const (
+	TIME_UNIT_HOUR  TimeUnit = "HOUR"  // 按小时统计，最多 24 小时
+	TIME_UNIT_DAY   TimeUnit = "DAY"   // 按天统计，最多 60 天
+	TIME_UNIT_MONTH TimeUnit = "MONTH" // 按月统计，最多 2 个月
+)
+
+func (t TimeUnit) String() string {
+	return string(t)
+}
+
+// layout returns the start time layout and the max duration of the time unit
+func (t TimeUnit) layout() (string, int) {
+	switch t {
+	case TIME_UNIT_HOUR:
+		return "2006-01-02 15", 24
+	case TIME_UNIT_DAY:
+		return "2006-01-02", 60
+	case TIME_UNIT_MONTH:
+		return "2006-01", 2
+	default:
+		return "", 0
+	}
+}
+
+// add returns t moved forward by n time units
+func (t TimeUnit) add(tm time.Time, n int) time.Time {
+	switch t {
+	case TIME_UNIT_HOUR:
+		return tm.Add(time.Duration(n) * time.Hour)
+	case TIME_UNIT_MONTH:
+		return tm.AddDate(0, n, 0)
+	default:
+		return tm.AddDate(0, 0, n)
+	}
+}
+
+// ReceivedDetail 送达统计详情
+type ReceivedDetail struct {
+	MsgID                 MsgID  `json:"msg_id"`                  // 消息 ID
+	JpushReceived         *int64 `json:"jpush_received"`          // 极光通道用户送达数
+	JpushOnlinePush       *int64 `json:"jpush_online_push"`       // 极光通道在线推送数
+	AndroidReceived       *int64 `json:"android_received"`        // Android 送达数（已废弃，保留兼容）
+	AndroidPnsSent        *int64 `json:"android_pns_sent"`        // Android 厂商通道推送成功数
+	AndroidPnsReceived    *int64 `json:"android_pns_received"`    // Android 厂商通道送达数
+	IosApnsReceived       *int64 `json:"ios_apns_received"`       // iOS 通知送达数
+	IosApnsSent           *int64 `json:"ios_apns_sent"`           // iOS 通知推送到 APNs 成功数
+	IosMsgReceived        *int64 `json:"ios_msg_received"`        // iOS 自定义消息送达数
+	WpMpnsSent            *int64 `json:"wp_mpns_sent"`            // winphone 通知送达数
+	QuickappJpushReceived *int64 `json:"quickapp_jpush_received"` // 快应用极光通道送达数
+	QuickappPnsSent       *int64 `json:"quickapp_pns_sent"`       // 快应用厂商通道推送成功数
+}
+
+// ReceivedDetails 送达统计详情列表，分批请求时 Timing 为各批请求耗时之和
+type ReceivedDetails struct {
+	ResponseTiming
+	Items []ReceivedDetail // 每个 msg_id 的送达统计
+}
+
+type MessageStatusRequest struct {
+	MsgID           MsgID    `json:"msg_id"`           // 消息 ID
+	RegistrationIDs []string `json:"registration_ids"` // 注册 ID 列表，最多 1000 个
+	Date            string   `json:"date,omitempty"`   // 查询日期，格式 yyyy-mm-dd，默认为当天
+}
+
+// MessageStatus 单个设备的送达状态
+type MessageStatus struct {
+	Status int `json:"status"` // 0：送达，1：未送达，2：registration_id 不属于该应用，3：registration_id 属于该应用但不是该条 message 的推送目标，4：系统异常
+}
+
+// MessageStatuses 设备送达状态查询结果
+type MessageStatuses struct {
+	ResponseTiming
+	Statuses map[string]MessageStatus // registration_id 与送达状态的对应关系
+}
+
+// ChannelDetail 推送通道统计
+type ChannelDetail struct {
+	Target     int64 `json:"target"`      // 推送目标数
+	OnlinePush int64 `json:"online_push"` // 在线推送数
+	Received   int64 `json:"received"`    // 送达数
+	Click      int64 `json:"click"`       // 点击数
+	MsgClick   int64 `json:"msg_click"`   // 自定义消息点击数
+}
+
+// VendorDetail 厂商通道统计
+type VendorDetail struct {
+	Target   int64 `json:"target"`   // 推送目标数
+	Sent     int64 `json:"sent"`     // 推送成功数
+	Received int64 `json:"received"` // 送达数
+	Click    int64 `json:"click"`    // 点击数
+}
+
+// AndroidPnsDetail Android 厂商通道统计
+type AndroidPnsDetail struct {
+	PnsTarget   int64         `json:"pns_target"`            // 厂商通道推送目标数
+	PnsSent     int64         `json:"pns_sent"`              // 厂商通道推送成功数
+	PnsReceived int64         `json:"pns_received"`          // 厂商通道送达数
+	PnsClick    int64         `json:"pns_click"`             // 厂商通道点击数
+	XmDetail    *VendorDetail `json:"xm_detail,omitempty"`   // 小米
+	HwDetail    *VendorDetail `json:"hw_detail,omitempty"`   // 华为
+	MzDetail    *VendorDetail `json:"mz_detail,omitempty"`   // 魅族
+	OppoDetail  *VendorDetail `json:"oppo_detail,omitempty"` // OPPO
+	VivoDetail  *VendorDetail `json:"vivo_detail,omitempty"` // vivo
+	FcmDetail   *VendorDetail `json:"fcm_detail,omitempty"`  // FCM
+	HonorDetail *VendorDetail `json:"ho_detail,omitempty"`   // 荣耀
+}
+
+// Vendors 返回厂商名称与统计的对应关系，仅包含有数据的厂商
+func (a *AndroidPnsDetail) Vendors() map[ThirdChannelType]*VendorDetail {
+	vendors := make(map[ThirdChannelType]*VendorDetail)
+	for channel, d := range map[ThirdChannelType]*VendorDetail{
+		XIAOMI: a.XmDetail,
+		HUAWEI: a.HwDetail,
+		MEIZU:  a.MzDetail,
+		OPPO:   a.OppoDetail,
+		VIVO:   a.VivoDetail,
+		FCM:    a.FcmDetail,
+		HONOR:  a.HonorDetail,
+	} {
+		if d != nil {
+			vendors[channel] = d
+		}
+	}
+	return vendors
+}
+
+// IosDetail iOS 通道统计
+type IosDetail struct {
+	ApnsTarget   int64 `json:"apns_target"`   // 通知推送目标数
+	ApnsSent     int64 `json:"apns_sent"`     // 推送到 APNs 成功数
+	ApnsReceived int64 `json:"apns_received"` // 通知送达数
+	ApnsClick    int64 `json:"apns_click"`    // 通知点击数
+	MsgTarget    int64 `json:"msg_target"`    // 自定义消息目标数
+	MsgReceived  int64 `json:"msg_received"`  // 自定义消息送达数
+}
+
+// MessageDetail 消息统计详情
+type MessageDetail struct {
+	MsgID         MsgID             `json:"msg_id"`                   // 消息 ID
+	Jpush         *ChannelDetail    `json:"jpush,omitempty"`          // 极光通道
+	AndroidPns    *AndroidPnsDetail `json:"android_pns,omitempty"`    // Android 厂商通道
+	Ios           *IosDetail        `json:"ios,omitempty"`            // iOS 通道
+	QuickappJpush *ChannelDetail    `json:"quickapp_jpush,omitempty"` // 快应用极光通道
+	QuickappPns   *VendorDetail     `json:"quickapp_pns,omitempty"`   // 快应用厂商通道
+}
+
+// MessageDetails 消息统计详情列表，分批请求时 Timing 为各批请求耗时之和
+type MessageDetails struct {
+	ResponseTiming
+	Items []MessageDetail // 每个 msg_id 的消息统计
+}
+
+// UserStat 用户统计
+type UserStat struct {
+	New    int64 `json:"new"`    // 新增用户
+	Online int64 `json:"online"` // 在线用户
+	Active int64 `json:"active"` // 活跃用户
+}
+
+type UsersItem struct {
+	Time    string    `json:"time"`              // 统计时间
+	Android *UserStat `json:"android,omitempty"` // Android 用户统计
+	Ios     *UserStat `json:"ios,omitempty"`     // iOS 用户统计
+}
+
+// UsersReport 用户统计报表
+type UsersReport struct {
+	ResponseTiming
+	TimeUnit TimeUnit    `json:"time_unit"` // 时间单位
+	Start    string      `json:"start"`     // 起始时间
+	Duration int         `json:"duration"`  // 持续时长
+	Items    []UsersItem `json:"items"`     // 统计项
+}
 
 // GetReport 获取消息推送结果
 func (j *JPushClient) GetReport(msg_ids string) (string, error) {
 	return j.sendGetReportRequest(msg_ids)
 }
 
+// GetReceivedDetail 获取送达统计详情，超过 100 个 msg_id 时自动分批请求
+func (j *JPushClient) GetReceivedDetail(msgIDs []int64) (*ReceivedDetails, error) {
+	return j.getReceivedDetail(context.Background(), msgIDs)
+}
+
+// GetMessagesDetail 获取消息统计详情（按平台与厂商细分），超过 100 个 msg_id 时自动分批请求
+func (j *JPushClient) GetMessagesDetail(msgIDs []int64) (*MessageDetails, error) {
+	return j.getMessagesDetail(context.Background(), msgIDs)
+}
+
+// GetMessageStatus 查询消息在指定设备上的送达状态，返回 registration_id 与状态的对应关系
+func (j *JPushClient) GetMessageStatus(r *MessageStatusRequest) (*MessageStatuses, error) {
+	if r == nil || r.MsgID <= 0 {
+		return nil, errors.New("msg id is required")
+	}
+	if len(r.RegistrationIDs) == 0 || len(r.RegistrationIDs) > 1000 {
+		return nil, errors.New("registration ids must contain 1 to 1000 items")
+	}
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := j.newRequest(http.MethodPost, HOST_REPORT_STATUS_MESSAGE)
+	req.SetBody(body)
+
+	ret := &MessageStatuses{}
+	if err := sendJSON(req, &ret.Statuses); err != nil {
+		return nil, err
+	}
+	ret.setTiming(req.Timing())
+	return ret, nil
+}
+
+// GetUsers 获取用户统计，start 按时间单位格式化，duration 取值范围：HOUR 1-24，DAY 1-60，MONTH 1-2
+func (j *JPushClient) GetUsers(unit TimeUnit, start time.Time, duration int) (*UsersReport, error) {
+	return j.getUsers(context.Background(), unit, start, duration)
+}
+
+func (j *JPushClient) getUsers(ctx context.Context, unit TimeUnit, start time.Time, duration int) (*UsersReport, error) {
+	layout, max := unit.layout()
+	if max == 0 {
+		return nil, errors.New("invalid time unit")
+	}
+	if duration <= 0 || duration > max {
+		return nil, errors.New("duration out of range")
+	}
+
+	req := j.newRequest(http.MethodGet, HOST_REPORT_USERS)
+	req.SetContext(ctx)
+	req.SetQueryParam("time_unit", unit.String())
+	req.SetQueryParam("start", start.Format(layout))
+	req.SetQueryParam("duration", strconv.Itoa(duration))
+
+	ret := &UsersReport{}
+	if err := sendJSON(req, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (j *JPushClient) getReceivedDetail(ctx context.Context, msgIDs []int64) (*ReceivedDetails, error) {
+	ret := &ReceivedDetails{}
+	err := chunkMsgIDs(msgIDs, func(ids string) error {
+		var details []ReceivedDetail
+		if err := j.sendReportRequest(ctx, HOST_REPORT_RECEIVED_DETAIL, ids, &details, &ret.ResponseTiming); err != nil {
+			return err
+		}
+		ret.Items = append(ret.Items, details...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (j *JPushClient) getMessagesDetail(ctx context.Context, msgIDs []int64) (*MessageDetails, error) {
+	ret := &MessageDetails{}
+	err := chunkMsgIDs(msgIDs, func(ids string) error {
+		var details []MessageDetail
+		if err := j.sendReportRequest(ctx, HOST_REPORT_MESSAGES_DETAIL, ids, &details, &ret.ResponseTiming); err != nil {
+			return err
+		}
+		ret.Items = append(ret.Items, details...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// sendReportRequest sends a get report request with msg_ids, decodes the response into v
+// and adds the request timing to timing
+func (j *JPushClient) sendReportRequest(ctx context.Context, url, msgIDs string, v interface{}, timing *ResponseTiming) error {
+	req := j.newRequest(http.MethodGet, url)
+	req.SetContext(ctx)
+	req.SetQueryParam("msg_ids", msgIDs)
+
+	if err := sendJSON(req, v); err != nil {
+		return err
+	}
+	timing.addTiming(req.Timing())
+	return nil
+}
+
+// chunkMsgIDs calls fn with comma separated msg ids, at most REPORT_MAX_MSG_IDS per call
+func chunkMsgIDs(msgIDs []int64, fn func(ids string) error) error {
+	if len(msgIDs) == 0 {
+		return errors.New("msg ids is empty")
+	}
+
+	for start := 0; start < len(msgIDs); start += REPORT_MAX_MSG_IDS {
+		end := start + REPORT_MAX_MSG_IDS
+		if end > len(msgIDs) {
+			end = len(msgIDs)
+		}
+
+		ids := make([]string, 0, end-start)
+		for _, id := range msgIDs[start:end] {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+
+		if err := fn(strings.Join(ids, ",")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // SendGetReportRequest sends a get report request and returns the response body as string
 func (j *JPushClient) sendGetReportRequest(msg_ids string) (string, error) {
-	req := Get(HOST_REPORT)
-	req.SetTimeout(DEFAULT_CONNECT_TIMEOUT*time.Second, DEFAULT_READ_WRITE_TIMEOUT*time.Second)
-	req.SetHeader("Connection", "Keep-Alive")
-	req.SetHeader("Charset", CHARSET)
-	req.SetBasicAuth(j.AppKey, j.MasterSecret)
-	req.SetHeader("Content-Type", CONTENT_TYPE_JSON)
-	req.SetProtocolVersion("HTTP/1.1")
+	req := j.newRequest(http.MethodGet, HOST_REPORT)
 	req.SetQueryParam("msg_ids", msg_ids)
 
 	return req.String()
@@ -0,0 +1,315 @@
+package jpush
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ReportFormat string
+
+const (
+	REPORT_FORMAT_CSV   ReportFormat = "csv"   // CSV，首行为表头
+	REPORT_FORMAT_JSONL ReportFormat = "jsonl" // JSON Lines，每行一个 JSON 对象
+)
+
+var (
+	messageReportColumns = []string{"msg_id", "platform", "channel", "target", "sent", "received", "click"}
+	usersReportColumns   = []string{"time", "platform", "new", "online", "active"}
+)
+
+// MessageReportRow 消息统计导出行，每条消息的每个平台/通道一行
+type MessageReportRow struct {
+	MsgID    MsgID  `json:"msg_id"`   // 消息 ID
+	Platform string `json:"platform"` // 平台：android、ios、winphone、quickapp
+	Channel  string `json:"channel"`  // 通道：jpush、pns（厂商合计）、apns、mpns 或厂商名称
+	Target   int64  `json:"target"`   // 推送目标数
+	Sent     int64  `json:"sent"`     // 推送成功数
+	Received int64  `json:"received"` // 送达数
+	Click    int64  `json:"click"`    // 点击数
+}
+
+// UsersReportRow 用户统计导出行，每个统计时间的每个平台一行
+type UsersReportRow struct {
+	Time     string `json:"time"`     // 统计时间
+	Platform string `json:"platform"` // 平台：android、ios
+	New      int64  `json:"new"`      // 新增用户
+	Online   int64  `json:"online"`   // 在线用户
+	Active   int64  `json:"active"`   // 活跃用户
+}
+
+func (r *MessageReportRow) record() []string {
+	return []string{
+		r.MsgID.String(), r.Platform, r.Channel,
+		strconv.FormatInt(r.Target, 10),
+		strconv.FormatInt(r.Sent, 10),
+		strconv.FormatInt(r.Received, 10),
+		strconv.FormatInt(r.Click, 10),
+	}
+}
+
+func (r *UsersReportRow) record() []string {
+	return []string{
+		r.Time, r.Platform,
+		strconv.FormatInt(r.New, 10),
+		strconv.FormatInt(r.Online, 10),
+		strconv.FormatInt(r.Active, 10),
+	}
+}
+
+// ReportExporter 将报表导出为 CSV 或 JSON Lines，按批次拉取并写出，不会在内存中保存完整结果
+type ReportExporter struct {
+	client *JPushClient
+	format ReportFormat
+	csv    *csv.Writer
+	json   *json.Encoder
+	header []string // CSV 已写出的表头，一个 CSV 导出器只能导出一种报表
+}
+
+// NewReportExporter 创建报表导出器，数据写入 w
+func (j *JPushClient) NewReportExporter(w io.Writer, format ReportFormat) (*ReportExporter, error) {
+	e := &ReportExporter{client: j, format: format}
+	switch format {
+	case REPORT_FORMAT_CSV:
+		e.csv = csv.NewWriter(w)
+	case REPORT_FORMAT_JSONL:
+		e.json = json.NewEncoder(w)
+	default:
+		return nil, errors.New("invalid report format")
+	}
+	return e, nil
+}
+
+// ExportMessages 导出消息的送达与点击统计，每批 100 个 msg_id 拉取 received/detail 与 messages/detail 后立即写出。
+// CSV 格式下不能与 ExportUsers 写入同一个导出器。
+func (e *ReportExporter) ExportMessages(ctx context.Context, msgIDs []int64) error {
+	if len(msgIDs) == 0 {
+		return errors.New("msg ids is empty")
+	}
+	if err := e.checkColumns(messageReportColumns); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(msgIDs); start += REPORT_MAX_MSG_IDS {
+		end := start + REPORT_MAX_MSG_IDS
+		if end > len(msgIDs) {
+			end = len(msgIDs)
+		}
+
+		received, err := e.client.getReceivedDetail(ctx, msgIDs[start:end])
+		if err != nil {
+			return err
+		}
+		details, err := e.client.getMessagesDetail(ctx, msgIDs[start:end])
+		if err != nil {
+			return err
+		}
+
+		if err := e.writeMessages(received.Items, details.Items); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportUsers 导出 [start, end] 时间范围内的用户统计，超过单次请求时长上限时自动分段请求。
+// CSV 格式下不能与 ExportMessages 写入同一个导出器。
+func (e *ReportExporter) ExportUsers(ctx context.Context, unit TimeUnit, start, end time.Time) error {
+	_, max := unit.layout()
+	if max == 0 {
+		return errors.New("invalid time unit")
+	}
+	if end.Before(start) {
+		return errors.New("end is before start")
+	}
+	if err := e.checkColumns(usersReportColumns); err != nil {
+		return err
+	}
+
+	for cur := start; !cur.After(end); {
+		n := 0
+		for n < max && !unit.add(cur, n).After(end) {
+			n++
+		}
+
+		report, err := e.client.getUsers(ctx, unit, cur, n)
+		if err != nil {
+			return err
+		}
+		if err := e.writeUsers(report); err != nil {
+			return err
+		}
+
+		cur = unit.add(cur, n)
+	}
+
+	return nil
+}
+
+// writeMessages flattens and writes one batch of message reports
+func (e *ReportExporter) writeMessages(received []ReceivedDetail, details []MessageDetail) error {
+	byID := make(map[MsgID]*MessageDetail, len(details))
+	for i := range details {
+		byID[details[i].MsgID] = &details[i]
+	}
+
+	seen := make(map[MsgID]bool, len(received))
+	for i := range received {
+		seen[received[i].MsgID] = true
+		for _, row := range messageReportRows(&received[i], byID[received[i].MsgID]) {
+			if err := e.writeRow(messageReportColumns, row.record(), row); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range details {
+		if seen[details[i].MsgID] {
+			continue
+		}
+		for _, row := range messageReportRows(nil, &details[i]) {
+			if err := e.writeRow(messageReportColumns, row.record(), row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.flush()
+}
+
+// writeUsers flattens and writes one users report
+func (e *ReportExporter) writeUsers(report *UsersReport) error {
+	for _, item := range report.Items {
+		for _, p := range []struct {
+			platform string
+			stat     *UserStat
+		}{{"android", item.Android}, {"ios", item.Ios}} {
+			if p.stat == nil {
+				continue
+			}
+			row := &UsersReportRow{Time: item.Time, Platform: p.platform, New: p.stat.New, Online: p.stat.Online, Active: p.stat.Active}
+			if err := e.writeRow(usersReportColumns, row.record(), row); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.flush()
+}
+
+func (e *ReportExporter) writeRow(columns, record []string, row interface{}) error {
+	if e.format == REPORT_FORMAT_JSONL {
+		return e.json.Encode(row)
+	}
+
+	if e.header == nil {
+		if err := e.csv.Write(columns); err != nil {
+			return err
+		}
+		e.header = columns
+	}
+	return e.csv.Write(record)
+}
+
+// checkColumns rejects writing a second kind of report into a CSV, the rows would not match the header
+func (e *ReportExporter) checkColumns(columns []string) error {
+	if e.format != REPORT_FORMAT_CSV || e.header == nil || strings.Join(e.header, ",") == strings.Join(columns, ",") {
+		return nil
+	}
+	return errors.New("a csv report exporter cannot mix message and users reports, use one exporter per report")
+}
+
+func (e *ReportExporter) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// messageReportRows flattens the reports of one message, messages detail wins over received detail
+func messageReportRows(received *ReceivedDetail, detail *MessageDetail) []*MessageReportRow {
+	var id MsgID
+	if received != nil {
+		id = received.MsgID
+	} else if detail != nil {
+		id = detail.MsgID
+	}
+
+	var rows []*MessageReportRow
+	add := func(platform, channel string, target, sent, rcv, click int64) {
+		rows = append(rows, &MessageReportRow{MsgID: id, Platform: platform, Channel: channel, Target: target, Sent: sent, Received: rcv, Click: click})
+	}
+
+	if detail != nil {
+		if d := detail.Jpush; d != nil {
+			add("android", "jpush", d.Target, d.OnlinePush, d.Received, d.Click)
+		}
+		if d := detail.AndroidPns; d != nil {
+			add("android", "pns", d.PnsTarget, d.PnsSent, d.PnsReceived, d.PnsClick)
+
+			vendors := d.Vendors()
+			names := make([]string, 0, len(vendors))
+			for name := range vendors {
+				names = append(names, name.String())
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				v := vendors[ThirdChannelType(name)]
+				add("android", name, v.Target, v.Sent, v.Received, v.Click)
+			}
+		}
+		if d := detail.Ios; d != nil {
+			add("ios", "apns", d.ApnsTarget, d.ApnsSent, d.ApnsReceived, d.ApnsClick)
+			add("ios", "jpush", d.MsgTarget, 0, d.MsgReceived, 0)
+		}
+		if d := detail.QuickappJpush; d != nil {
+			add("quickapp", "jpush", d.Target, d.OnlinePush, d.Received, d.Click)
+		}
+		if d := detail.QuickappPns; d != nil {
+			add("quickapp", "pns", d.Target, d.Sent, d.Received, d.Click)
+		}
+	}
+
+	if received != nil {
+		if detail == nil {
+			if received.JpushReceived != nil || received.JpushOnlinePush != nil {
+				add("android", "jpush", 0, int64Value(received.JpushOnlinePush), int64Value(received.JpushReceived), 0)
+			}
+			if received.AndroidPnsSent != nil || received.AndroidPnsReceived != nil {
+				add("android", "pns", 0, int64Value(received.AndroidPnsSent), int64Value(received.AndroidPnsReceived), 0)
+			}
+			if received.IosApnsSent != nil || received.IosApnsReceived != nil {
+				add("ios", "apns", 0, int64Value(received.IosApnsSent), int64Value(received.IosApnsReceived), 0)
+			}
+			if received.IosMsgReceived != nil {
+				add("ios", "jpush", 0, 0, int64Value(received.IosMsgReceived), 0)
+			}
+			if received.QuickappJpushReceived != nil {
+				add("quickapp", "jpush", 0, 0, int64Value(received.QuickappJpushReceived), 0)
+			}
+			if received.QuickappPnsSent != nil {
+				add("quickapp", "pns", 0, int64Value(received.QuickappPnsSent), 0, 0)
+			}
+		}
+		// winphone is only reported by received detail
+		if received.WpMpnsSent != nil {
+			add("winphone", "mpns", 0, int64Value(received.WpMpnsSent), 0, 0)
+		}
+	}
+
+	return rows
+}
+
+func int64Value(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
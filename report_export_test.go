@@ -0,0 +1,123 @@
+package jpush
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportExporterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewJPushClient("appKey", "masterSecret").NewReportExporter(&buf, REPORT_FORMAT_CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := int64(3)
+	received := []ReceivedDetail{{MsgID: 1, WpMpnsSent: &sent}, {MsgID: 2, IosApnsReceived: &sent}}
+	details := []MessageDetail{{
+		MsgID:      1,
+		Jpush:      &ChannelDetail{Target: 10, OnlinePush: 8, Received: 7, Click: 1},
+		AndroidPns: &AndroidPnsDetail{PnsTarget: 5, PnsSent: 5, PnsReceived: 4, HwDetail: &VendorDetail{Target: 2}, XmDetail: &VendorDetail{Target: 3}},
+	}}
+	if err := e.writeMessages(received, details); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"msg_id,platform,channel,target,sent,received,click",
+		"1,android,jpush,10,8,7,1",
+		"1,android,pns,5,5,4,0",
+		"1,android,huawei,2,0,0,0",
+		"1,android,xiaomi,3,0,0,0",
+		"1,winphone,mpns,0,3,0,0",
+		"2,ios,apns,0,0,3,0",
+	}, "\n") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReportExporterJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewJPushClient("appKey", "masterSecret").NewReportExporter(&buf, REPORT_FORMAT_JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := &UsersReport{Items: []UsersItem{{Time: "2024-01-01", Android: &UserStat{New: 1, Online: 2, Active: 3}}}}
+	if err := e.writeUsers(report); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"time":"2024-01-01","platform":"android","new":1,"online":2,"active":3}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
+
+func TestReportExporterExport(t *testing.T) {
+	var requests []string
+	c := NewJPushClient("appKey", "masterSecret")
+	c.SetTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		q := req.URL.Query()
+		requests = append(requests, req.URL.Path+"?"+q.Get("msg_ids")+q.Get("start")+"/"+q.Get("duration"))
+
+		var body string
+		switch req.URL.Path {
+		case "/v3/received/detail":
+			body = `[{"msg_id":"1","ios_apns_received":2}]`
+		case "/v3/messages/detail":
+			body = `[{"msg_id":"1","jpush":{"target":10,"online_push":8,"received":7,"click":1}}]`
+		case "/v3/users":
+			body = `{"time_unit":"DAY","start":"` + q.Get("start") + `","items":[{"time":"` + q.Get("start") + `","android":{"new":1,"online":2,"active":3}}]}`
+		}
+		return stubResponse(req, http.StatusOK, body), nil
+	}))
+
+	var messages bytes.Buffer
+	e, err := c.NewReportExporter(&messages, REPORT_FORMAT_CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, REPORT_MAX_MSG_IDS+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	if err := e.ExportMessages(context.Background(), ids); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.ExportMessages(context.Background(), []int64{1}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := e.ExportUsers(context.Background(), TIME_UNIT_DAY, start, start); err == nil {
+		t.Fatal("users reports should not be written below the message header")
+	}
+
+	// one header, one row per batch and message
+	want := "msg_id,platform,channel,target,sent,received,click\n" + strings.Repeat("1,android,jpush,10,8,7,1\n", 3)
+	if got := messages.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if len(requests) != 6 || !strings.HasSuffix(requests[2], "?101/") {
+		t.Fatalf("requests = %v, want two batches of msg ids", requests)
+	}
+
+	requests = nil
+	var users bytes.Buffer
+	u, _ := c.NewReportExporter(&users, REPORT_FORMAT_CSV)
+	if err := u.ExportUsers(context.Background(), TIME_UNIT_DAY, start, start.AddDate(0, 0, 60)); err != nil {
+		t.Fatal(err)
+	}
+	want = "time,platform,new,online,active\n2024-01-01,android,1,2,3\n2024-03-01,android,1,2,3\n"
+	if got := users.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if strings.Join(requests, ",") != "/v3/users?2024-01-01/60,/v3/users?2024-03-01/1" {
+		t.Fatalf("requests = %v, want the range split at 60 days", requests)
+	}
+}
@@ -0,0 +1,51 @@
+package jpush
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestChunkMsgIDs(t *testing.T) {
+	ids := make([]int64, 250)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	var chunks []string
+	err := chunkMsgIDs(ids, func(s string) error {
+		chunks = append(chunks, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	for i, want := range []int{100, 100, 50} {
+		if n := len(strings.Split(chunks[i], ",")); n != want {
+			t.Errorf("chunk %d has %d ids, want %d", i, n, want)
+		}
+	}
+	if !strings.HasPrefix(chunks[2], "201,") || !strings.HasSuffix(chunks[2], ",250") {
+		t.Errorf("unexpected last chunk %q", chunks[2])
+	}
+
+	if err := chunkMsgIDs(nil, func(string) error { return nil }); err == nil {
+		t.Error("expected error for empty msg ids")
+	}
+}
+
+func TestMsgIDUnmarshal(t *testing.T) {
+	var v struct {
+		A MsgID `json:"a"`
+		B MsgID `json:"b"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":"54043195528353612","b":18100}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 54043195528353612 || v.B != 18100 {
+		t.Errorf("got %d %d", v.A, v.B)
+	}
+}
@@ -0,0 +1,206 @@
+package jpush
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+type WatchStopReason string
+
+const (
+	WATCH_STABLE   WatchStopReason = "stable"   // 统计数据已稳定
+	WATCH_DEADLINE WatchStopReason = "deadline" // 达到最长观察时间
+	WATCH_CANCELED WatchStopReason = "canceled" // context 被取消
+)
+
+// ReportWatchOptions 推送统计观察参数，零值字段使用默认值
+type ReportWatchOptions struct {
+	Interval     time.Duration // 首次轮询间隔，默认 10 秒
+	MaxInterval  time.Duration // 最大轮询间隔，默认 5 分钟
+	Multiplier   float64       // 轮询间隔增长倍数，默认 2
+	Deadline     time.Duration // 最长观察时间，默认 1 小时
+	StableRounds int           // 连续多少次统计数据不变视为稳定，默认 3，统计数据为空时不计入
+}
+
+// ReportSnapshot 某一时刻的推送统计快照
+type ReportSnapshot struct {
+	MsgID    MsgID           // 消息 ID
+	Time     time.Time       // 采集时间
+	Received *ReceivedDetail // 送达统计
+	Detail   *MessageDetail  // 按平台与厂商细分的送达与点击统计
+	Err      error           // 本次轮询的错误，发生错误时继续按计划轮询
+	Final    bool            // 是否为最后一个快照
+	Reason   WatchStopReason // 停止原因，仅在 Final 为 true 时有值
+}
+
+func (o *ReportWatchOptions) withDefaults() ReportWatchOptions {
+	ret := ReportWatchOptions{}
+	if o != nil {
+		ret = *o
+	}
+	if ret.Interval <= 0 {
+		ret.Interval = 10 * time.Second
+	}
+	if ret.MaxInterval < ret.Interval {
+		ret.MaxInterval = 5 * time.Minute
+		if ret.MaxInterval < ret.Interval {
+			ret.MaxInterval = ret.Interval
+		}
+	}
+	if ret.Multiplier < 1 {
+		ret.Multiplier = 2
+	}
+	if ret.Deadline <= 0 {
+		ret.Deadline = time.Hour
+	}
+	if ret.StableRounds <= 0 {
+		ret.StableRounds = 3
+	}
+	return ret
+}
+
+// WatchReport 按退避间隔轮询消息的送达与点击统计，通过 channel 返回快照。
+// 统计数据非空且连续 StableRounds 次不变、达到 Deadline 或 ctx 被取消时停止，最后一个快照的 Final 为 true，随后 channel 关闭。
+func (j *JPushClient) WatchReport(ctx context.Context, msgID int64, opts *ReportWatchOptions) <-chan ReportSnapshot {
+	o := opts.withDefaults()
+	ch := make(chan ReportSnapshot, 1)
+
+	go func() {
+		defer close(ch)
+
+		watchCtx, cancel := context.WithTimeout(ctx, o.Deadline)
+		defer cancel()
+
+		// the final snapshot is best effort once the caller has canceled ctx
+		finish := func(snap ReportSnapshot, reason WatchStopReason) {
+			snap.Final, snap.Reason = true, reason
+			select {
+			case ch <- snap:
+			case <-ctx.Done():
+			}
+		}
+
+		var last *ReportSnapshot
+		stable := 0
+		interval := o.Interval
+
+		for {
+			snap := j.reportSnapshot(watchCtx, msgID)
+			if watchCtx.Err() != nil {
+				if last != nil {
+					snap = *last
+				}
+				snap.Err = watchCtx.Err()
+				finish(snap, watchStopReason(watchCtx))
+				return
+			}
+
+			if snap.Err == nil {
+				// JPush answers with empty reports until the first counts arrive, that is not stable yet
+				if last != nil && snap.hasCounts() && reflect.DeepEqual(last.Received, snap.Received) && reflect.DeepEqual(last.Detail, snap.Detail) {
+					stable++
+				} else {
+					stable = 0
+				}
+				last = &snap
+			}
+
+			if stable >= o.StableRounds {
+				finish(snap, WATCH_STABLE)
+				return
+			}
+
+			select {
+			case ch <- snap:
+			case <-watchCtx.Done():
+				snap.Err = watchCtx.Err()
+				finish(snap, watchStopReason(watchCtx))
+				return
+			}
+
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-watchCtx.Done():
+				timer.Stop()
+				final := ReportSnapshot{MsgID: MsgID(msgID), Time: time.Now()}
+				if last != nil {
+					final = *last
+				}
+				final.Err = watchCtx.Err()
+				finish(final, watchStopReason(watchCtx))
+				return
+			}
+
+			interval = time.Duration(float64(interval) * o.Multiplier)
+			if interval > o.MaxInterval {
+				interval = o.MaxInterval
+			}
+		}
+	}()
+
+	return ch
+}
+
+// reportSnapshot fetches the received and messages detail reports of msgID
+func (j *JPushClient) reportSnapshot(ctx context.Context, msgID int64) ReportSnapshot {
+	snap := ReportSnapshot{MsgID: MsgID(msgID), Time: time.Now()}
+
+	received, err := j.getReceivedDetail(ctx, []int64{msgID})
+	if err != nil {
+		snap.Err = err
+		return snap
+	}
+	if len(received.Items) > 0 {
+		snap.Received = &received.Items[0]
+	}
+
+	details, err := j.getMessagesDetail(ctx, []int64{msgID})
+	if err != nil {
+		snap.Err = err
+		return snap
+	}
+	if len(details.Items) > 0 {
+		snap.Detail = &details.Items[0]
+	}
+
+	return snap
+}
+
+// hasCounts reports whether the snapshot holds any non-zero count
+func (s *ReportSnapshot) hasCounts() bool {
+	return hasCounts(reflect.ValueOf(s.Received)) || hasCounts(reflect.ValueOf(s.Detail))
+}
+
+// hasCounts reports whether v holds a non-zero integer, msg ids are not counts
+func hasCounts(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return !v.IsNil() && hasCounts(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Type != reflect.TypeOf(MsgID(0)) && hasCounts(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if hasCounts(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() != 0
+	}
+	return false
+}
+
+// watchStopReason tells whether the watch context ended by its deadline or by the caller
+func watchStopReason(ctx context.Context) WatchStopReason {
+	if ctx.Err() == context.DeadlineExceeded {
+		return WATCH_DEADLINE
+	}
+	return WATCH_CANCELED
+}
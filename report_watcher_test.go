@@ -0,0 +1,121 @@
+package jpush
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// watchTransport answers the detail reports with the bodies returned by received for the n-th poll
+func watchTransport(received func(n int) string) (http.RoundTripper, func() []time.Time) {
+	var mu sync.Mutex
+	var polls []time.Time
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		if req.URL.Path == "/v3/received/detail" {
+			polls = append(polls, time.Now())
+		}
+		n := len(polls)
+		mu.Unlock()
+
+		body := `[]`
+		if req.URL.Path == "/v3/received/detail" {
+			body = received(n)
+		}
+		return stubResponse(req, http.StatusOK, body), nil
+	})
+	return rt, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Time(nil), polls...)
+	}
+}
+
+func TestWatchReportStableAndBackoff(t *testing.T) {
+	rt, polls := watchTransport(func(n int) string {
+		switch {
+		case n <= 2:
+			return `[]`
+		case n == 3:
+			return `[{"msg_id":"1","jpush_received":null}]`
+		default:
+			return `[{"msg_id":"1","jpush_received":5}]`
+		}
+	})
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(rt)
+
+	opts := &ReportWatchOptions{Interval: 5 * time.Millisecond, MaxInterval: 20 * time.Millisecond, StableRounds: 2}
+	var snaps []ReportSnapshot
+	for snap := range c.WatchReport(context.Background(), 1, opts) {
+		snaps = append(snaps, snap)
+	}
+
+	// empty reports never count as stable, the counts have to stay the same for two more polls
+	last := snaps[len(snaps)-1]
+	if !last.Final || last.Reason != WATCH_STABLE || len(snaps) != 6 || *last.Received.JpushReceived != 5 {
+		t.Fatalf("%d snapshots, last = %+v", len(snaps), last)
+	}
+
+	times := polls()
+	want := []time.Duration{5, 10, 20, 20, 20}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < want[i-1]*time.Millisecond {
+			t.Fatalf("poll %d came after %v, want at least %v", i, gap, want[i-1]*time.Millisecond)
+		}
+	}
+}
+
+func TestWatchReportDeadlineAndCancel(t *testing.T) {
+	rt, _ := watchTransport(func(int) string { return `[]` })
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(rt)
+
+	opts := &ReportWatchOptions{Interval: time.Millisecond, MaxInterval: time.Millisecond, StableRounds: 1, Deadline: 30 * time.Millisecond}
+	var last ReportSnapshot
+	for snap := range c.WatchReport(context.Background(), 1, opts) {
+		last = snap
+	}
+	if !last.Final || last.Reason != WATCH_DEADLINE {
+		t.Fatalf("last = %+v, empty reports should be watched until the deadline", last)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := c.WatchReport(ctx, 1, &ReportWatchOptions{Interval: time.Hour})
+	if snap := <-ch; snap.Final {
+		t.Fatalf("first snapshot = %+v", snap)
+	}
+	cancel()
+	select {
+	case snap, ok := <-ch:
+		if ok && (!snap.Final || snap.Reason != WATCH_CANCELED) {
+			t.Fatalf("snapshot after cancel = %+v", snap)
+		}
+		for range ch {
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the watch did not stop after ctx was canceled")
+	}
+}
+
+func TestWatchReportFinalSnapshotErr(t *testing.T) {
+	rt, _ := watchTransport(func(int) string { return `[{"msg_id":"1","jpush_received":5}]` })
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(rt)
+
+	// the deadline ends the watch while it waits for the next poll
+	ch := c.WatchReport(context.Background(), 1, &ReportWatchOptions{Interval: time.Hour, Deadline: 30 * time.Millisecond})
+	first := <-ch
+	if first.Final || first.Err != nil {
+		t.Fatalf("first snapshot = %+v", first)
+	}
+	final, ok := <-ch
+	if !ok || !final.Final || final.Reason != WATCH_DEADLINE || final.Err != context.DeadlineExceeded {
+		t.Fatalf("final snapshot = %+v, want the deadline error", final)
+	}
+	if *final.Received.JpushReceived != 5 {
+		t.Fatalf("final snapshot = %+v, want the last counts", final)
+	}
+}
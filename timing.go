@@ -0,0 +1,139 @@
+package jpush
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// HTTPTiming 单次请求的耗时分解
+type HTTPTiming struct {
+	DNS          time.Duration // DNS 解析耗时，复用连接时为 0
+	Connect      time.Duration // TCP 建立连接耗时，复用连接时为 0
+	TLSHandshake time.Duration // TLS 握手耗时，复用连接时为 0
+	TTFB         time.Duration // 从开始请求到收到第一个响应字节的耗时
+	Total        time.Duration // 从开始请求到读完响应体的总耗时
+	Reused       bool          // 是否复用了连接池中的连接
+}
+
+// ResponseTiming 嵌入在接口返回结果中，记录返回该结果的请求耗时
+type ResponseTiming struct {
+	Timing HTTPTiming `json:"-"` // 请求耗时
+}
+
+func (r *ResponseTiming) setTiming(t HTTPTiming) {
+	r.Timing = t
+}
+
+// addTiming adds the timing of one more request to a result built from several requests,
+// the connection counts as reused only when every request reused one
+func (r *ResponseTiming) addTiming(t HTTPTiming) {
+	if r.Timing == (HTTPTiming{}) {
+		r.Timing = t
+		return
+	}
+	r.Timing.DNS += t.DNS
+	r.Timing.Connect += t.Connect
+	r.Timing.TLSHandshake += t.TLSHandshake
+	r.Timing.TTFB += t.TTFB
+	r.Timing.Total += t.Total
+	r.Timing.Reused = r.Timing.Reused && t.Reused
+}
+
+// timed is implemented by the typed responses embedding ResponseTiming
+type timed interface {
+	setTiming(HTTPTiming)
+}
+
+// RequestInfo 请求完成后传给中间件的信息
+type RequestInfo struct {
+	Method     string     // 请求方法
+	URL        string     // 请求地址
+	Family     APIFamily  // 接口类别，不属于任何类别时为空
+	StatusCode int        // 响应状态码，请求失败时为 0
+	Err        error      // 请求失败的原因
+	Timing     HTTPTiming // 请求耗时
+}
+
+// Middleware 在客户端的每个请求完成后调用，用于记录日志、指标等，需要并发安全
+type Middleware func(info *RequestInfo)
+
+// Use 为客户端注册中间件，需要在发送请求前调用
+func (j *JPushClient) Use(middlewares ...Middleware) {
+	j.middlewares = append(j.middlewares, middlewares...)
+}
+
+// requestTiming collects the httptrace events of a request
+type requestTiming struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       HTTPTiming
+	done         bool
+}
+
+// trace returns the client trace recording into t, the request starts now
+func (t *requestTiming) trace() *httptrace.ClientTrace {
+	t.mu.Lock()
+	t.start = time.Now()
+	t.timing = HTTPTiming{}
+	t.done = false
+	t.mu.Unlock()
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mark(func() { t.timing.DNS = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.mark(func() { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			t.mark(func() { t.timing.Connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			t.mark(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mark(func() { t.timing.TLSHandshake = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mark(func() { t.timing.Reused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			t.mark(func() { t.timing.TTFB = time.Since(t.start) })
+		},
+	}
+}
+
+func (t *requestTiming) mark(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fn()
+}
+
+// finish records the total duration once, it reports whether this call finished the request
+func (t *requestTiming) finish() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done || t.start.IsZero() {
+		return false
+	}
+	t.done = true
+	t.timing.Total = time.Since(t.start)
+	return true
+}
+
+func (t *requestTiming) get() HTTPTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.timing
+}
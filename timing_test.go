@@ -0,0 +1,141 @@
+package jpush
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestTiming(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"cidlist":["c1"]}`))
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var infos []*RequestInfo
+	c := NewJPushClient("key", "secret")
+	c.Use(func(info *RequestInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		infos = append(infos, info)
+	})
+
+	ret := &CidResponse{}
+	if err := sendJSON(c.newRequest(http.MethodGet, srv.URL+"/v3/push/cid"), ret); err != nil {
+		t.Fatal(err)
+	}
+
+	timing := ret.Timing
+	if timing.Connect <= 0 || timing.TTFB < 5*time.Millisecond || timing.Total < timing.TTFB || timing.Reused {
+		t.Fatalf("timing = %+v", timing)
+	}
+
+	if len(infos) != 1 {
+		t.Fatalf("middleware called %d times, want 1", len(infos))
+	}
+	info := infos[0]
+	if info.Family != API_PUSH || info.StatusCode != http.StatusOK || info.Err != nil || info.Timing != timing {
+		t.Fatalf("info = %+v", info)
+	}
+
+	srv.Close()
+	if _, err := c.newRequest(http.MethodGet, srv.URL+"/v3/push/cid").Bytes(); err == nil {
+		t.Fatal("request to a closed server should fail")
+	}
+	if len(infos) != 2 || infos[1].Err == nil || infos[1].StatusCode != 0 {
+		t.Fatalf("failed request info = %+v", infos[len(infos)-1])
+	}
+}
+
+func TestResultTiming(t *testing.T) {
+	c := NewJPushClient("key", "secret")
+	c.SetTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		time.Sleep(time.Millisecond)
+
+		var body string
+		switch req.URL.Path {
+		case "/v3/push/cid":
+			n, _ := strconv.Atoi(req.URL.Query().Get("count"))
+			cids := make([]string, n)
+			for i := range cids {
+				cids[i] = fmt.Sprintf(`"cid%d"`, i)
+			}
+			body = `{"cidlist":[` + strings.Join(cids, ",") + `]}`
+		case "/v3/push/batch/regid/single":
+			var sent struct {
+				PushList map[string]json.RawMessage `json:"pushlist"`
+			}
+			data, _ := io.ReadAll(req.Body)
+			_ = json.Unmarshal(data, &sent)
+			results := make([]string, 0, len(sent.PushList))
+			for cid := range sent.PushList {
+				results = append(results, fmt.Sprintf(`%q:{"msg_id":"1"}`, cid))
+			}
+			body = "{" + strings.Join(results, ",") + "}"
+		case "/v3/push":
+			body = `{"sendno":"0","msg_id":"1"}`
+		case "/v3/received/detail", "/v3/messages/detail":
+			body = `[{"msg_id":"1"}]`
+		case "/v3/status/message":
+			body = `{"regid":{"status":0}}`
+		case "/v3/geofences":
+			body = `{"geofences":[{"geofence_id":"g1"}]}`
+		}
+		return stubResponse(req, http.StatusOK, body), nil
+	}))
+
+	// reports split into two requests add up their timings
+	ids := make([]int64, REPORT_MAX_MSG_IDS+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	received, err := c.GetReceivedDetail(ids)
+	if err != nil || len(received.Items) != 2 || received.Timing.Total < 2*time.Millisecond {
+		t.Fatalf("received = %+v, %v", received, err)
+	}
+	details, err := c.GetMessagesDetail(ids[:1])
+	if err != nil || len(details.Items) != 1 || details.Timing.Total <= 0 {
+		t.Fatalf("details = %+v, %v", details, err)
+	}
+	status, err := c.GetMessageStatus(&MessageStatusRequest{MsgID: 1, RegistrationIDs: []string{"regid"}})
+	if err != nil || len(status.Statuses) != 1 || status.Timing.Total <= 0 {
+		t.Fatalf("status = %+v, %v", status, err)
+	}
+	fences, err := c.ListGeofences(1)
+	if err != nil || len(fences.Geofences) != 1 || fences.Timing.Total <= 0 {
+		t.Fatalf("geofences = %+v, %v", fences, err)
+	}
+
+	p := NewPayLoad()
+	p.SetNotification(&Notification{Alert: "hi"})
+	batch, err := c.BatchPush(BATCH_REGID, map[string]*PayLoad{"r1": p, "r2": p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for target, r := range batch {
+		if r.Error != nil || r.Timing.Total <= 0 {
+			t.Fatalf("batch result of %s = %+v", target, r)
+		}
+	}
+
+	p.SetPlatform(&Platform{})
+	many, err := c.PushToMany(context.Background(), p, ALIAS, []string{"a1", "a2"}, &PushToManyOptions{ChunkSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range many.Chunks {
+		if chunk.Error != nil || chunk.Timing.Total <= 0 {
+			t.Fatalf("chunk = %+v", chunk)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package jpush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// JPush 撤销推送返回的错误码
+const (
+	ERR_CODE_WITHDRAW_EXPIRED  = 1031 // 消息超过可撤销的时间
+	ERR_CODE_ALREADY_WITHDRAWN = 1032 // 消息已经撤销
+)
+
+var (
+	ErrWithdrawExpired  = errors.New("jpush: message is too old to withdraw")
+	ErrAlreadyWithdrawn = errors.New("jpush: message already withdrawn")
+)
+
+// WithdrawError 撤销推送失败的错误。
+// 可通过 errors.Is(err, ErrWithdrawExpired) / errors.Is(err, ErrAlreadyWithdrawn) 判断失败原因，
+// errors.As 可取得 JPush 返回的 *APIError。
+type WithdrawError struct {
+	MsgID  MsgID     // 消息 ID
+	Reason error     // ErrWithdrawExpired、ErrAlreadyWithdrawn，无法识别时为 nil
+	API    *APIError // JPush 返回的错误
+}
+
+func (e *WithdrawError) Error() string {
+	if e.Reason != nil {
+		return fmt.Sprintf("withdraw %s: %v: %v", e.MsgID, e.Reason, e.API)
+	}
+	return fmt.Sprintf("withdraw %s: %v", e.MsgID, e.API)
+}
+
+func (e *WithdrawError) Unwrap() []error {
+	errs := []error{e.API}
+	if e.Reason != nil {
+		errs = append(errs, e.Reason)
+	}
+	return errs
+}
+
+// withdrawReason classifies the JPush error code of a failed withdraw
+func withdrawReason(e *APIError) error {
+	switch e.Code {
+	case ERR_CODE_WITHDRAW_EXPIRED:
+		return ErrWithdrawExpired
+	case ERR_CODE_ALREADY_WITHDRAWN:
+		return ErrAlreadyWithdrawn
+	default:
+		return nil
+	}
+}
+
+// WithdrawPush 撤销推送，仅支持撤销一天内的消息
+func (j *JPushClient) WithdrawPush(msgID int64) error {
+	if msgID <= 0 {
+		return errors.New("invalid msg id")
+	}
+
+	req := j.newRequest(http.MethodDelete, fmt.Sprintf("%s/%d/withdraw", HOST_PUSH, msgID))
+
+	err := sendJSON(req, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return &WithdrawError{MsgID: MsgID(msgID), Reason: withdrawReason(apiErr), API: apiErr}
+	}
+	return err
+}
+
+// GetScheduleMsgIDs 获取定时任务已产生的消息 ID
+func (j *JPushClient) GetScheduleMsgIDs(scheduleID string) ([]MsgID, error) {
+	if scheduleID == "" {
+		return nil, errors.New("schedule id is empty")
+	}
+
+	req := j.newRequest(http.MethodGet, HOST_SCHEDULE+"/"+url.PathEscape(scheduleID)+"/msg_ids")
+
+	// msgids is either a list of ids or a list of {"msg_id": ...} objects depending on the API version
+	var resp struct {
+		MsgIDs []json.RawMessage `json:"msgids"`
+	}
+	if err := sendJSON(req, &resp); err != nil {
+		return nil, err
+	}
+
+	ret := make([]MsgID, 0, len(resp.MsgIDs))
+	for _, raw := range resp.MsgIDs {
+		var item struct {
+			MsgID MsgID `json:"msg_id"`
+		}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			var id MsgID
+			if err := json.Unmarshal(raw, &id); err != nil {
+				return nil, err
+			}
+			item.MsgID = id
+		}
+		if item.MsgID > 0 {
+			ret = append(ret, item.MsgID)
+		}
+	}
+	return ret, nil
+}
+
+// WithdrawSchedule 撤销定时任务已产生的所有消息，返回每个消息 ID 的撤销结果，成功时为 nil
+func (j *JPushClient) WithdrawSchedule(scheduleID string) (map[MsgID]error, error) {
+	ids, err := j.GetScheduleMsgIDs(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[MsgID]error, len(ids))
+	for _, id := range ids {
+		ret[id] = j.WithdrawPush(int64(id))
+	}
+	return ret, nil
+}
@@ -0,0 +1,62 @@
+package jpush
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestWithdrawPush(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		reason error
+	}{
+		{"ok", http.StatusOK, `{}`, nil},
+		{"expired", http.StatusBadRequest, `{"error":{"code":1031,"message":"msgid exceeds the withdraw time"}}`, ErrWithdrawExpired},
+		{"withdrawn", http.StatusBadRequest, `{"error":{"code":1032,"message":"msgid has been withdrawn"}}`, ErrAlreadyWithdrawn},
+		{"rate limit", http.StatusTooManyRequests, `{"error":{"code":2002,"message":"Request times exceed the limit"}}`, nil},
+		{"unknown", http.StatusBadRequest, `{"error":{"code":1003,"message":"msgid is invalid"}}`, nil},
+		{"not json", http.StatusBadGateway, `bad gateway`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method, path string
+			c := NewJPushClient("key", "secret")
+			c.SetTransport(stubTransport(tt.status, tt.body, func(req *http.Request) {
+				method, path = req.Method, req.URL.Path
+			}))
+
+			err := c.WithdrawPush(123)
+			if method != http.MethodDelete || path != "/v3/push/123/withdraw" {
+				t.Fatalf("request = %s %s", method, path)
+			}
+			if tt.status == http.StatusOK {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var werr *WithdrawError
+			if !errors.As(err, &werr) || werr.MsgID != 123 || werr.API.StatusCode != tt.status {
+				t.Fatalf("err = %v, want a *WithdrawError", err)
+			}
+			if werr.Reason != tt.reason {
+				t.Fatalf("reason = %v, want %v", werr.Reason, tt.reason)
+			}
+			if tt.reason != nil && !errors.Is(err, tt.reason) {
+				t.Fatalf("errors.Is(%v, %v) = false", err, tt.reason)
+			}
+			if errors.Is(err, ErrWithdrawExpired) != (tt.reason == ErrWithdrawExpired) {
+				t.Fatalf("err = %v is classified as expired", err)
+			}
+		})
+	}
+
+	if err := NewJPushClient("key", "secret").WithdrawPush(0); err == nil {
+		t.Fatal("an invalid msg id should be rejected")
+	}
+}